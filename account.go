@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Limits of account credentials
+const (
+	MaxNicknameLength    = 20
+	MinPasswordLength    = 6
+	LoginTokenSize       = 32
+	MaxLoginTokensNumber = 10
+	// Bcrypt passwords longer than 72 bytes are not allowed
+	MaxPasswordBytes = 72
+)
+
+// Errors of account store which are safe to show for clients
+var (
+	errAccountNicknameIsTaken  = errors.New(errorNicknameIsTaken)
+	errAccountInvalidLogin     = errors.New(errorInvalidCredentials)
+	errAccountInvalidNickname  = errors.New(errorInvalidNickname)
+	errAccountPasswordTooShort = errors.New(errorPasswordIsTooShort)
+	errAccountPasswordTooLong  = errors.New(errorPasswordIsTooLong)
+)
+
+// Account is a registered player who can log in with password or with login token.
+type Account struct {
+	Id           uint64    `json:"id"`
+	Nickname     string    `json:"nickname"`
+	PasswordHash string    `json:"passwordHash"`
+	TokenHashes  []string  `json:"tokenHashes"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// AccountLogin is the result of registration or login of the client
+type AccountLogin struct {
+	client       *Client
+	isByPassword bool
+	account      *Account
+	token        string
+	err          error
+}
+
+// AccountStore keeps registered accounts
+type AccountStore interface {
+	// Creates a new account and returns it with a new login token
+	Register(nickname string, password string) (account *Account, token string, err error)
+	// Checks password and returns the account with a new login token
+	LoginWithPassword(nickname string, password string) (account *Account, token string, err error)
+	// Returns the account which owns the token
+	LoginWithToken(token string) (account *Account, err error)
+	// Checks if nickname belongs to a registered account
+	IsNicknameRegistered(nickname string) bool
+	// Returns the account by id or nil if it does not exist
+	GetAccount(accountId uint64) *Account
+}
+
+// AccountFileStore keeps accounts in a local JSON file
+type AccountFileStore struct {
+	path          string
+	mutex         sync.Mutex
+	lastAccountId uint64
+	accounts      []*Account
+}
+
+// NewAccountFileStore loads accounts from the file; the file is created with the first account
+func NewAccountFileStore(path string) (*AccountFileStore, error) {
+	s := &AccountFileStore{
+		path:     path,
+		accounts: make([]*Account, 0),
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &s.accounts); err != nil {
+		return nil, fmt.Errorf("cannot parse accounts file %s: %s", path, err)
+	}
+	for _, a := range s.accounts {
+		if a.Id > s.lastAccountId {
+			s.lastAccountId = a.Id
+		}
+	}
+
+	return s, nil
+}
+
+// Register creates a new account with unique nickname
+func (s *AccountFileStore) Register(nickname string, password string) (*Account, string, error) {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" || utf8.RuneCountInString(nickname) > MaxNicknameLength {
+		return nil, "", errAccountInvalidNickname
+	}
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return nil, "", errAccountPasswordTooShort
+	}
+	if len(password) > MaxPasswordBytes {
+		return nil, "", errAccountPasswordTooLong
+	}
+
+	// Hashing is slow, so other calls are not blocked by it
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.findByNickname(nickname) != nil {
+		return nil, "", errAccountNicknameIsTaken
+	}
+
+	s.lastAccountId++
+	account := &Account{
+		Id:           s.lastAccountId,
+		Nickname:     nickname,
+		PasswordHash: passwordHash,
+		TokenHashes:  make([]string, 0),
+		CreatedAt:    time.Now(),
+	}
+	token, err := s.addToken(account)
+	if err != nil {
+		return nil, "", err
+	}
+	s.accounts = append(s.accounts, account)
+
+	return account, token, s.save()
+}
+
+// LoginWithPassword checks password of the account and issues a new login token
+func (s *AccountFileStore) LoginWithPassword(nickname string, password string) (*Account, string, error) {
+	s.mutex.Lock()
+	account := s.findByNickname(strings.TrimSpace(nickname))
+	var passwordHash string
+	if account != nil {
+		passwordHash = account.PasswordHash
+	}
+	s.mutex.Unlock()
+
+	if account == nil {
+		return nil, "", errAccountInvalidLogin
+	}
+	// Checking is slow, so other calls are not blocked by it
+	if !checkPassword(passwordHash, password) {
+		return nil, "", errAccountInvalidLogin
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	token, err := s.addToken(account)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return account, token, s.save()
+}
+
+// LoginWithToken finds account by login token which was issued before
+func (s *AccountFileStore) LoginWithToken(token string) (*Account, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tokenHash := hashToken(token)
+	for _, a := range s.accounts {
+		for _, h := range a.TokenHashes {
+			if subtle.ConstantTimeCompare([]byte(h), []byte(tokenHash)) == 1 {
+				return a, nil
+			}
+		}
+	}
+
+	return nil, errAccountInvalidLogin
+}
+
+// IsNicknameRegistered checks if nickname belongs to an account ignoring case
+func (s *AccountFileStore) IsNicknameRegistered(nickname string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.findByNickname(strings.TrimSpace(nickname)) != nil
+}
+
+// GetAccount returns the account by id or nil if it does not exist
+func (s *AccountFileStore) GetAccount(accountId uint64) *Account {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, a := range s.accounts {
+		if a.Id == accountId {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *AccountFileStore) findByNickname(nickname string) *Account {
+	for _, a := range s.accounts {
+		if strings.EqualFold(a.Nickname, nickname) {
+			return a
+		}
+	}
+	return nil
+}
+
+// Adds a new token to the account and forgets the oldest ones over the limit
+func (s *AccountFileStore) addToken(account *Account) (string, error) {
+	token, err := generateRandomHex(LoginTokenSize)
+	if err != nil {
+		return "", err
+	}
+	account.TokenHashes = append(account.TokenHashes, hashToken(token))
+	if len(account.TokenHashes) > MaxLoginTokensNumber {
+		account.TokenHashes = account.TokenHashes[len(account.TokenHashes)-MaxLoginTokensNumber:]
+	}
+	return token, nil
+}
+
+func (s *AccountFileStore) save() error {
+	return writeJsonFile(s.path, s.accounts)
+}
+
+func generateRandomHex(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func checkPassword(passwordHash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
+
+// Tokens are stored as hashes, so the file does not allow to log in
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
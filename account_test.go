@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAccountFileStore(t *testing.T) (store *AccountFileStore, path string) {
+	dir, err := ioutil.TempDir("", "durak_accounts")
+	if err != nil {
+		t.Fatalf("Cannot create temp dir: %s", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path = filepath.Join(dir, "accounts.json")
+	store, err = NewAccountFileStore(path)
+	if err != nil {
+		t.Fatalf("Cannot create account store: %s", err)
+	}
+	return store, path
+}
+
+func TestRegisterAccount(t *testing.T) {
+	store, _ := newTestAccountFileStore(t)
+	account, token, err := store.Register(" Alice ", "secret1")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(uint64(1), account.Id)
+	assert.Equal("Alice", account.Nickname)
+	assert.NotEmpty(token)
+	assert.True(store.IsNicknameRegistered("alice"))
+
+	_, _, err = store.Register("ALICE", "secret2")
+	assert.Equal(errAccountNicknameIsTaken, err)
+	_, _, err = store.Register("Bob", "123")
+	assert.Equal(errAccountPasswordTooShort, err)
+	_, _, err = store.Register("  ", "secret1")
+	assert.Equal(errAccountInvalidNickname, err)
+}
+
+func TestLoginWithPasswordAndToken(t *testing.T) {
+	store, _ := newTestAccountFileStore(t)
+	registered, _, _ := store.Register("Alice", "secret1")
+
+	assert := assert.New(t)
+	_, _, err := store.LoginWithPassword("Alice", "wrong password")
+	assert.Equal(errAccountInvalidLogin, err)
+
+	account, token, err := store.LoginWithPassword("alice", "secret1")
+	assert.Nil(err)
+	assert.Equal(registered.Id, account.Id)
+
+	account, err = store.LoginWithToken(token)
+	assert.Nil(err)
+	assert.Equal(registered.Id, account.Id)
+
+	_, err = store.LoginWithToken("unknown")
+	assert.Equal(errAccountInvalidLogin, err)
+}
+
+func TestAccountsSurviveReload(t *testing.T) {
+	store, path := newTestAccountFileStore(t)
+	store.Register("Alice", "secret1")
+	_, token, _ := store.Register("Bob", "secret2")
+
+	reloadedStore, err := NewAccountFileStore(path)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	account, err := reloadedStore.LoginWithToken(token)
+	assert.Nil(err)
+	assert.Equal("Bob", account.Nickname)
+
+	account, _, err = reloadedStore.Register("Carol", "secret3")
+	assert.Nil(err)
+	assert.Equal(uint64(3), account.Id)
+}
+
+func TestLobbyChecksPasswordOutsideOfLobbyGoroutine(t *testing.T) {
+	lobby, _, _ := newTestLobbyWithGame()
+	store, _ := newTestAccountFileStore(t)
+	lobby.accountStore = store
+	store.Register("Carol", "secret1")
+	client := &Client{id: 3, lobby: lobby, isValid: true, send: make(chan []byte, 10)}
+	lobby.clients[client] = true
+
+	lobby.onLoginCommand(client, &LobbyLoginCommandData{Nickname: "Carol", Password: "secret1"})
+	accountLogin := <-lobby.accountLogins
+	lobby.onAccountLogin(accountLogin)
+
+	assert := assert.New(t)
+	assert.Equal("Carol", client.Nickname())
+	assert.Equal(uint64(1), client.AccountId())
+
+	disconnectedClient := &Client{id: 4, lobby: lobby}
+	lobby.onLoginCommand(disconnectedClient, &LobbyLoginCommandData{Token: accountLogin.token})
+	lobby.onAccountLogin(<-lobby.accountLogins)
+	assert.Empty(disconnectedClient.Nickname())
+}
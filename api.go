@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Serves stats of players by account id: GET /api/players/{id}/stats
+func serveApiPlayers(lobby *Lobby, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/players/"), "/")
+	if len(pathParts) != 2 || pathParts[1] != "stats" {
+		http.Error(w, "Not found", 404)
+		return
+	}
+	accountId, err := strconv.ParseUint(pathParts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+
+	account := lobby.accountStore.GetAccount(accountId)
+	if account == nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+	stats := lobby.statsStore.GetStats(accountId)
+	if stats == nil {
+		stats = &PlayerStats{AccountId: accountId}
+	}
+	rating := getRatingToShow(lobby.ratingStore.GetRating(accountId))
+
+	writeApiResponse(w, stats.toPlayerStatsInfo(account.Nickname, rating))
+}
+
+// Serves leaderboard: GET /api/leaderboards?period=week&orderBy=winRatio
+func serveApiLeaderboards(lobby *Lobby, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = LeaderboardPeriodAllTime
+	}
+	orderBy := r.URL.Query().Get("orderBy")
+	if orderBy == "" {
+		orderBy = LeaderboardOrderRating
+	}
+	if !isValidLeaderboardPeriod(period) || !isValidLeaderboardOrder(orderBy) {
+		http.Error(w, "Bad request", 400)
+		return
+	}
+
+	writeApiResponse(w, lobby.getLeaderboard(period, orderBy, time.Now()))
+}
+
+// Serves games from logs: GET /api/games?month=202001&player=Alice&loser=Bob&withBots=false&limit=20
+// and one game with all entries: GET /api/games/{id}
+func serveApiGames(gameLogDir string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	gameId := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/games"), "/")
+	if gameId != "" {
+		history, err := readGameHistory(gameLogDir, gameId)
+		if os.IsNotExist(err) {
+			http.Error(w, "Not found", 404)
+			return
+		}
+		if err != nil {
+			log.Printf("Cannot read game %s: %s", gameId, err)
+			http.Error(w, "Internal server error", 500)
+			return
+		}
+		writeApiResponse(w, history)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &GameHistoryFilter{
+		Month:  query.Get("month"),
+		Player: query.Get("player"),
+		Loser:  query.Get("loser"),
+		Limit:  GameHistoryDefaultLimit,
+	}
+	if filter.Month != "" && !gameLogMonthRegexp.MatchString(filter.Month) {
+		http.Error(w, "Bad request", 400)
+		return
+	}
+	if withBots := query.Get("withBots"); withBots != "" {
+		withBotsBool, err := strconv.ParseBool(withBots)
+		if err != nil {
+			http.Error(w, "Bad request", 400)
+			return
+		}
+		filter.WithBots = &withBotsBool
+	}
+	if limit := query.Get("limit"); limit != "" {
+		limitInt, err := strconv.Atoi(limit)
+		if err != nil || limitInt < 1 || limitInt > GameHistoryMaxLimit {
+			http.Error(w, "Bad request", 400)
+			return
+		}
+		filter.Limit = limitInt
+	}
+
+	summaries, err := listGameHistory(gameLogDir, filter)
+	if err != nil {
+		log.Printf("Cannot list games: %s", err)
+		http.Error(w, "Internal server error", 500)
+		return
+	}
+	writeApiResponse(w, summaries)
+}
+
+func writeApiResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Cannot write API response: %s", err)
+	}
+}
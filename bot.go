@@ -3,10 +3,33 @@ package main
 import (
 	"encoding/json"
 	"log"
+	"math/rand"
 )
 
 const additionalAttackStopIndex = float64(0.25)
 
+// Difficulty levels of bots
+const (
+	// Makes random legal moves
+	BotLevelEasy = "easy"
+	// Plays the lowest cards and stops throwing in when cards on table are strong
+	BotLevelNormal = "normal"
+	// Plays as normal and counts cards to choose attacks and throw-ins
+	BotLevelHard = "hard"
+	// Searches moves by simulating games with information-set Monte Carlo tree search
+	BotLevelExpert = "expert"
+	// Makes moves which human players made most often in saved games, plays as normal without trained policy
+	BotLevelTrained = "trained"
+)
+
+func isValidBotLevel(level string) bool {
+	switch level {
+	case BotLevelEasy, BotLevelNormal, BotLevelHard, BotLevelExpert, BotLevelTrained:
+		return true
+	}
+	return false
+}
+
 // Bot represents a AI player
 type Bot struct {
 	botClient         *BotClient
@@ -18,6 +41,18 @@ type Bot struct {
 	myUnbeatenCards   map[Card]bool
 	iAmPickingUp      bool
 	initialPlayersNum int
+	gameRules         *GameRules
+	level             string
+	// Seen cards, they are used by hard and expert bots
+	memory       *botMemory
+	roundsPlayed int
+	// Search runs in its own goroutine and sends the decision back
+	decisions   chan *ismctsDecision
+	isSearching bool
+	// Increases with each event, so decisions for old states are dropped
+	stateVersion int
+	// Frequencies of decisions of human players, they are used by trained bots
+	policy *BotPolicy
 }
 
 func newBot(botClient *BotClient) *Bot {
@@ -26,6 +61,11 @@ func newBot(botClient *BotClient) *Bot {
 		yourPlayerIndex: -1,
 		players:         make([]*Player, 0),
 		myUnbeatenCards: make(map[Card]bool, 0),
+		gameRules:       newGameRules(),
+		level:           botClient.level,
+		memory:          newBotMemory(),
+		decisions:       make(chan *ismctsDecision),
+		policy:          botClient.getBotPolicy(),
 	}
 }
 
@@ -34,6 +74,8 @@ func (b *Bot) run() {
 		select {
 		case event := <-b.botClient.incomingEvents:
 			b.dispatchEvent(event)
+		case decision := <-b.decisions:
+			b.onSearchDecision(decision)
 		}
 	}
 }
@@ -61,6 +103,14 @@ func (b *Bot) dispatchEvent(message []byte) {
 				b.onGamePlayersEvent(parsedEvent)
 			}, err
 		},
+		"GameDealEvent": func(b *Bot) (func(), error) {
+			var parsedEvent GameDealEvent
+			err = json.Unmarshal(eventDataJson, &parsedEvent)
+
+			return func() {
+				b.onGameDealEvent(parsedEvent)
+			}, err
+		},
 		"GameFirstAttackerEvent": func(b *Bot) (func(), error) {
 			var parsedEvent GameFirstAttackerEvent
 			err = json.Unmarshal(eventDataJson, &parsedEvent)
@@ -93,6 +143,14 @@ func (b *Bot) dispatchEvent(message []byte) {
 				b.onGameDefendEvent(parsedEvent)
 			}, err
 		},
+		"GameTransferEvent": func(bot *Bot) (func(), error) {
+			var parsedEvent GameTransferEvent
+			err = json.Unmarshal(eventDataJson, &parsedEvent)
+
+			return func() {
+				b.onGameTransferEvent(parsedEvent)
+			}, err
+		},
 		"GameStateEvent": func(bot *Bot) (func(), error) {
 			var parsedEvent GameStateEvent
 			err = json.Unmarshal(eventDataJson, &parsedEvent)
@@ -132,6 +190,7 @@ func (b *Bot) dispatchEvent(message []byte) {
 	}
 
 	finishExec()
+	b.stateVersion++
 	b.makeDecision()
 }
 
@@ -140,12 +199,24 @@ func (b *Bot) onGamePlayersEvent(event GamePlayersEvent) {
 	b.yourPlayerIndex = event.YourPlayerIndex
 }
 
+func (b *Bot) onGameDealEvent(event GameDealEvent) {
+	b.gameStateInfo = event.GameStateInfo
+	b.memory = newBotMemory()
+	b.roundsPlayed = 0
+	if event.TrumpCardIsOwnedByPlayerIndex >= 0 && event.TrumpCardIsOwnedByPlayerIndex != b.yourPlayerIndex {
+		b.memory.addPlayerCard(event.TrumpCardIsOwnedByPlayerIndex, event.GameStateInfo.TrumpCard)
+	}
+}
+
 func (b *Bot) onGameFirstAttackerEvent(event GameFirstAttackerEvent) {
 	b.gameStateInfo = event.GameStateInfo
 }
 
 func (b *Bot) onGameStartedEvent(event GameStartedEvent) {
 	b.gameStateInfo = event.GameStateInfo
+	if event.GameRules != nil {
+		b.gameRules = event.GameRules
+	}
 	b.gameWasStarted = true
 	b.gameIsOver = false
 	b.initialPlayersNum = len(b.players)
@@ -153,11 +224,22 @@ func (b *Bot) onGameStartedEvent(event GameStartedEvent) {
 
 func (b *Bot) onGameAttackEvent(event GameAttackEvent) {
 	b.gameStateInfo = event.GameStateInfo
+	b.memory.onCardPlayed(event.AttackerIndex, event.Card)
 }
 
 func (b *Bot) onGameDefendEvent(event GameDefendEvent) {
 	b.gameStateInfo = event.GameStateInfo
 	delete(b.myUnbeatenCards, *event.AttackingCard)
+	b.memory.onCardPlayed(event.DefenderIndex, event.DefendingCard)
+}
+
+func (b *Bot) onGameTransferEvent(event GameTransferEvent) {
+	b.gameStateInfo = event.GameStateInfo
+	b.memory.onCardPlayed(event.TransferrerIndex, event.Card)
+	if event.DefenderIndex == b.yourPlayerIndex {
+		// Our attacking cards came back to us, now we have to defend them
+		b.myUnbeatenCards = make(map[Card]bool, 0)
+	}
 }
 
 func (b *Bot) onGameStateEvent(event GameStateEvent) {
@@ -165,11 +247,54 @@ func (b *Bot) onGameStateEvent(event GameStateEvent) {
 }
 
 func (b *Bot) onNewRoundEvent(event NewRoundEvent) {
+	if b.gameStateInfo != nil && event.GameStateInfo != nil {
+		b.memory.onRoundEnded(b.gameStateInfo, event.WasAttackSuccessful)
+		b.rememberTrumpCardOwner(b.gameStateInfo, event.GameStateInfo, event.WasAttackSuccessful)
+	}
+	b.roundsPlayed++
 	b.myUnbeatenCards = make(map[Card]bool, 0)
 	b.iAmPickingUp = false
 	b.gameStateInfo = event.GameStateInfo
 }
 
+// The trump card is the last card of the deck, it goes to the last player who draws cards.
+// Cards are drawn by the attacker first, then by other players and by the defender at last.
+func (b *Bot) rememberTrumpCardOwner(before *GameStateInfo, after *GameStateInfo, wasAttackSuccessful bool) {
+	if before.DeckSize == 0 || after.DeckSize > 0 || before.TrumpCard == nil {
+		return
+	}
+	if len(before.HandsSizes) != len(after.HandsSizes) {
+		return
+	}
+	tableCardsNum := len(before.Battleground) + len(before.DefendingCards)
+	hasDrawn := func(playerIndex int) bool {
+		handSize := before.HandsSizes[playerIndex]
+		if wasAttackSuccessful && playerIndex == before.DefenderIndex {
+			handSize += tableCardsNum
+		}
+		return after.HandsSizes[playerIndex] > handSize
+	}
+
+	dealOrder := []int{before.AttackerIndex}
+	for index := range before.HandsSizes {
+		if index != before.AttackerIndex && index != before.DefenderIndex {
+			dealOrder = append(dealOrder, index)
+		}
+	}
+	dealOrder = append(dealOrder, before.DefenderIndex)
+
+	for i := len(dealOrder) - 1; i >= 0; i-- {
+		playerIndex := dealOrder[i]
+		if playerIndex < 0 || playerIndex >= len(before.HandsSizes) || !hasDrawn(playerIndex) {
+			continue
+		}
+		if playerIndex != b.yourPlayerIndex {
+			b.memory.addPlayerCard(playerIndex, before.TrumpCard)
+		}
+		return
+	}
+}
+
 func (b *Bot) onGameEndEvent() {
 	b.gameIsOver = true
 }
@@ -230,10 +355,17 @@ func (b *Bot) getAvailableCardsForAttack() (cards []*Card) {
 func (b *Bot) attack() bool {
 	availableCards := b.getAvailableCardsForAttack()
 	minimalValueCard := b.findLowestCard(availableCards)
+	isUnbeatable := false
+	if b.level == BotLevelHard {
+		minimalValueCard, isUnbeatable = b.chooseCountedAttackCard(availableCards)
+		if minimalValueCard == nil {
+			return false
+		}
+	}
 
 	// Should bot add card to strong cards on table?
 	battlegroundPickUpValue := b.getTablePickUpValue(minimalValueCard)
-	if battlegroundPickUpValue > additionalAttackStopIndex {
+	if battlegroundPickUpValue > additionalAttackStopIndex && !isUnbeatable {
 		return false
 	}
 
@@ -286,6 +418,68 @@ func (b *Bot) defend() {
 	}
 }
 
+func (b *Bot) canTransfer() bool {
+	if !b.gameStateInfo.CanYouTransfer {
+		return false
+	}
+	if len(b.getAvailableCardsForTransfer()) == 0 {
+		return false
+	}
+
+	return true
+}
+
+func (b *Bot) getAvailableCardsForTransfer() (cards []*Card) {
+	if len(b.gameStateInfo.Battleground) == 0 {
+		return
+	}
+	value := b.gameStateInfo.Battleground[0].Value
+	for _, cardOnHand := range b.gameStateInfo.YourHand {
+		if cardOnHand.Value == value {
+			cards = append(cards, cardOnHand)
+		}
+	}
+
+	return
+}
+
+func (b *Bot) transfer() bool {
+	minimalValueCard := b.findLowestCard(b.getAvailableCardsForTransfer())
+
+	// Keep trump card if bot can beat attacking cards without it
+	if minimalValueCard.Suit == b.gameStateInfo.TrumpCard.Suit && b.canBeatAllAttackingCards() {
+		return false
+	}
+
+	transferActionData := TransferActionData{Card: minimalValueCard}
+	b.botClient.sendGameAction(PlayerActionNameTransfer, transferActionData)
+	b.myUnbeatenCards[*minimalValueCard] = true
+
+	return true
+}
+
+func (b *Bot) canBeatAllAttackingCards() bool {
+	trumpSuit := b.gameStateInfo.TrumpCard.Suit
+	usedCards := make(map[Card]bool, 0)
+	for _, attackCard := range b.getAttackingCardsToDefend() {
+		defendCandidates := make([]*Card, 0)
+		for _, hCard := range b.gameStateInfo.YourHand {
+			if usedCards[*hCard] {
+				continue
+			}
+			if hCard.Suit == trumpSuit && attackCard.Suit != trumpSuit || hCard.gt(attackCard) {
+				defendCandidates = append(defendCandidates, hCard)
+			}
+		}
+		if len(defendCandidates) == 0 {
+			return false
+		}
+		usedCards[*b.findLowestCard(defendCandidates)] = true
+	}
+
+	return true
+}
+
 func (b *Bot) complete() {
 	b.botClient.sendGameAction(PlayerActionNameComplete, nil)
 }
@@ -303,6 +497,19 @@ func (b *Bot) makeDecision() {
 		return
 	}
 
+	if b.level == BotLevelEasy {
+		b.makeRandomDecision()
+		return
+	}
+	if settings, ok := botSearchSettings[b.level]; ok {
+		b.makeSearchDecision(settings)
+		return
+	}
+	if b.level == BotLevelTrained && b.policy != nil {
+		b.makePolicyDecision()
+		return
+	}
+
 	if b.gameStateInfo.DefenderPickUp {
 		b.myUnbeatenCards = make(map[Card]bool, 0)
 	}
@@ -317,14 +524,21 @@ func (b *Bot) makeDecision() {
 
 		}
 		// Should bot add card to strong cards on table?
+		// Hard bots check each card by themselves
 		battlegroundPickUpValue := b.getTablePickUpValue(nil)
-		if battlegroundPickUpValue < additionalAttackStopIndex {
+		if battlegroundPickUpValue < additionalAttackStopIndex || b.level == BotLevelHard {
 			if b.attack() {
 				return
 			}
 		}
 	}
 
+	if b.canTransfer() {
+		if b.transfer() {
+			return
+		}
+	}
+
 	if b.canDefend() {
 		b.defend()
 		return
@@ -335,6 +549,187 @@ func (b *Bot) makeDecision() {
 	}
 }
 
+// Starts search of the move in other goroutine, so the bot keeps receiving events while it thinks
+func (b *Bot) makeSearchDecision(settings *BotSearchSettings) {
+	if b.isSearching {
+		return
+	}
+	search := newIsmctsSearch(b, settings)
+	actions := search.getLegalActions()
+	if len(actions) == 0 {
+		return
+	}
+	if len(actions) == 1 {
+		b.sendAction(actions[0])
+		return
+	}
+
+	b.isSearching = true
+	stateVersion := b.stateVersion
+	go func() {
+		b.decisions <- &ismctsDecision{action: search.run(), stateVersion: stateVersion}
+	}()
+}
+
+func (b *Bot) onSearchDecision(decision *ismctsDecision) {
+	b.isSearching = false
+	if decision.stateVersion != b.stateVersion {
+		// The game has changed while the bot was thinking
+		if b.isGameStateValid() {
+			b.makeDecision()
+		}
+		return
+	}
+	b.sendAction(decision.action)
+}
+
+// Makes the move which human players chose most often in the same situation
+func (b *Bot) makePolicyDecision() {
+	// Hidden cards are dealt randomly, they do not change moves of the bot but keep numbers of cards of others
+	state := newIsmctsSearch(b, nil).determinize()
+	if state.getWaitingPlayerIndex() != b.yourPlayerIndex {
+		return
+	}
+	actions := state.getLegalActions(b.yourPlayerIndex)
+	if len(actions) == 0 {
+		return
+	}
+	b.sendAction(b.policy.chooseAction(state, actions))
+}
+
+func (b *Bot) sendAction(action GameAction) {
+	if action.Name == PlayerActionNamePickUp {
+		b.pickUp()
+		return
+	}
+	b.botClient.sendGameAction(action.Name, action.Data)
+}
+
+// Makes one of legal moves by chance
+func (b *Bot) makeRandomDecision() {
+	if b.gameStateInfo.DefenderPickUp {
+		b.myUnbeatenCards = make(map[Card]bool, 0)
+	}
+
+	moves := make([]func(), 0)
+	if b.canAttack() {
+		for _, card := range b.getAvailableCardsForAttack() {
+			attackActionData := AttackActionData{Card: card}
+			moves = append(moves, func() {
+				b.botClient.sendGameAction(PlayerActionNameAttack, attackActionData)
+				b.myUnbeatenCards[*attackActionData.Card] = true
+			})
+		}
+	}
+	if b.canTransfer() {
+		for _, card := range b.getAvailableCardsForTransfer() {
+			transferActionData := TransferActionData{Card: card}
+			moves = append(moves, func() {
+				b.botClient.sendGameAction(PlayerActionNameTransfer, transferActionData)
+				b.myUnbeatenCards[*transferActionData.Card] = true
+			})
+		}
+	}
+	if b.canDefend() && !b.iAmPickingUp {
+		trumpSuit := b.gameStateInfo.TrumpCard.Suit
+		for _, attackCard := range b.getAttackingCardsToDefend() {
+			for _, hCard := range b.gameStateInfo.YourHand {
+				if hCard.Suit == trumpSuit && attackCard.Suit != trumpSuit || hCard.gt(attackCard) {
+					defendActionData := DefendActionData{AttackingCard: attackCard, DefendingCard: hCard}
+					moves = append(moves, func() {
+						b.botClient.sendGameAction(PlayerActionNameDefend, defendActionData)
+					})
+				}
+			}
+		}
+		if b.gameStateInfo.CanYouPickUp {
+			moves = append(moves, b.pickUp)
+		}
+	}
+	if b.gameStateInfo.CanYouComplete {
+		moves = append(moves, b.complete)
+	}
+
+	if len(moves) == 0 {
+		return
+	}
+	moves[rand.Intn(len(moves))]()
+}
+
+// Chooses the lowest card which the defender cannot beat according to counted cards.
+// Otherwise it is the lowest card, of the scarce suit for the first attack.
+// Trumps are kept while the deck is not empty or when the defender picks up cards.
+func (b *Bot) chooseCountedAttackCard(cards []*Card) (card *Card, isUnbeatable bool) {
+	gsi := b.gameStateInfo
+	trumpSuit := gsi.TrumpCard.Suit
+	nonTrumpCards := make([]*Card, 0)
+	for _, c := range cards {
+		if c.Suit != trumpSuit {
+			nonTrumpCards = append(nonTrumpCards, c)
+		}
+	}
+
+	if gsi.DefenderPickUp {
+		// All thrown in cards go to the defender, so it is the time to get rid of low cards
+		return b.findLowestCard(nonTrumpCards), true
+	}
+
+	if gsi.DefenderIndex >= 0 && gsi.DefenderIndex < len(gsi.HandsSizes) {
+		unknownCards := b.memory.getUnknownCards(gsi, b.gameRules.DeckSize)
+		handSize := gsi.HandsSizes[gsi.DefenderIndex]
+		unbeatableCards := make([]*Card, 0)
+		for _, c := range cards {
+			if c.Suit == trumpSuit && gsi.DeckSize > 0 {
+				continue
+			}
+			if !b.memory.canPlayerBeat(gsi.DefenderIndex, handSize, c, unknownCards, trumpSuit) {
+				unbeatableCards = append(unbeatableCards, c)
+			}
+		}
+		if len(unbeatableCards) > 0 {
+			return b.findLowestCard(unbeatableCards), true
+		}
+	}
+
+	if len(gsi.Battleground) > 0 {
+		return b.findLowestCard(cards), false
+	}
+	return b.findLowestCardOfScarceSuit(cards), false
+}
+
+// Finds the lowest card of the suit which has the least unknown cards, other players are less likely to beat it.
+// Trumps are used only when there are no other cards.
+func (b *Bot) findLowestCardOfScarceSuit(cards []*Card) *Card {
+	trumpSuit := b.gameStateInfo.TrumpCard.Suit
+	knownSuitCards := make(map[string]int, 0)
+	for card := range b.memory.discardedCards {
+		knownSuitCards[card.Suit]++
+	}
+	for _, card := range b.gameStateInfo.YourHand {
+		knownSuitCards[card.Suit]++
+	}
+
+	var found *Card
+	for _, card := range cards {
+		if found == nil {
+			found = card
+			continue
+		}
+		if (card.Suit == trumpSuit) != (found.Suit == trumpSuit) {
+			if found.Suit == trumpSuit {
+				found = card
+			}
+			continue
+		}
+		if card.getValueIndex() < found.getValueIndex() ||
+			card.getValueIndex() == found.getValueIndex() && knownSuitCards[card.Suit] > knownSuitCards[found.Suit] {
+			found = card
+		}
+	}
+
+	return found
+}
+
 func (b *Bot) hasBattlegroundSameValue(card *Card) bool {
 	for _, c := range b.gameStateInfo.Battleground {
 		if c.Value == card.Value {
@@ -393,8 +788,9 @@ func (b *Bot) getTablePickUpValue(additionalCard *Card) float64 {
 // How many cards left in deck: 0..1: 0 - empty deck; 1 - full deck.
 func (b *Bot) getDeckRemainsIndex() float64 {
 	deckRemainsIndex := float64(0)
-	if b.initialPlayersNum < 6 {
-		deckRemainsIndex = float64(b.gameStateInfo.DeckSize) / float64(36-b.initialPlayersNum*6)
+	deckSizeAfterDeal := b.gameRules.DeckSize - b.initialPlayersNum*b.gameRules.HandSize
+	if deckSizeAfterDeal > 0 {
+		deckRemainsIndex = float64(b.gameStateInfo.DeckSize) / float64(deckSizeAfterDeal)
 	}
 
 	return deckRemainsIndex
@@ -402,12 +798,14 @@ func (b *Bot) getDeckRemainsIndex() float64 {
 
 // Calculate power of cards on table in range 0..1, where 1 is maximum possible cards power
 func (b *Bot) getCardsOnTablePowerRate(additionalCard *Card) float64 {
-	// Each card has attack rate from 0 ("6") to 9 ("A")
-	// Each trump card has attack rate from 10 (trump "6") to 18 (trump "A")
+	// Each card has attack rate from 0 (the lowest value of deck, e.g. "6") to N-1 ("A"),
+	// where N is number of values in deck: 6 for 24 cards, 9 for 36 cards, 13 for 52 cards.
+	// Each trump card has attack rate from N (the lowest trump) to 2N (trump "A")
 	// maxAttackRate - cards with highest value: trump "A", "K", "Q", ...
 	// We need this value to get attack rate of battleground in range of 0..1
+	valuesNum := len(getDeckValues(b.gameRules.DeckSize))
 	maxAttackRate := 0
-	maxAttackRatePerCurrentCard := 18 // Trump "A" has maximum attack rate
+	maxAttackRatePerCurrentCard := 2 * valuesNum // Trump "A" has maximum attack rate
 
 	totalCardsOnTable := len(b.gameStateInfo.Battleground) + len(b.gameStateInfo.DefendingCards)
 	if additionalCard != nil {
@@ -420,9 +818,9 @@ func (b *Bot) getCardsOnTablePowerRate(additionalCard *Card) float64 {
 
 	getCardAttackRate := func(card *Card) int {
 		if card.Suit == b.gameStateInfo.TrumpCard.Suit {
-			return card.getValueIndex() + 9
+			return card.getValueIndexInDeck(b.gameRules.DeckSize) + valuesNum
 		}
-		return card.getValueIndex()
+		return card.getValueIndexInDeck(b.gameRules.DeckSize)
 	}
 
 	battlegroundAttackRate := 0
@@ -1,25 +1,33 @@
 package main
 
 import (
-	"log"
 	"math/rand"
 )
 
+// BotAction contains a move of the bot which the lobby passes to the game of the room
+type BotAction struct {
+	bot    *BotClient
+	action *PlayerAction
+}
+
 // BotClient represents a connection to the game for an AI player
 type BotClient struct {
 	nickname string
 	id       uint64
 	room     *Room
+	// Difficulty level which selects strategy of the bot
+	level string
 
 	incomingEvents  chan []byte
 	outgoingActions chan *PlayerAction
 }
 
-func newBotClient(id uint64, room *Room) *BotClient {
+func newBotClient(id uint64, room *Room, level string) *BotClient {
 	botClient := &BotClient{
 		nickname: generateBotName(),
 		id:       id,
 		room:     room,
+		level:    level,
 		// We use buffered channel because a decision of one bot produces game events for this bot too
 		incomingEvents:  make(chan []byte, MaxPlayersInRoom*MaxPlayersInRoom+1),
 		outgoingActions: make(chan *PlayerAction),
@@ -32,6 +40,14 @@ func newBotClient(id uint64, room *Room) *BotClient {
 	return botClient
 }
 
+// Returns the policy of trained bots which is loaded by the lobby
+func (bl *BotClient) getBotPolicy() *BotPolicy {
+	if bl.room == nil || bl.room.lobby == nil {
+		return nil
+	}
+	return bl.room.lobby.botPolicy
+}
+
 func (bl *BotClient) sendEvent(event interface{}) {
 	jsonEvent, _ := eventToJSON(event)
 	bl.sendMessage(jsonEvent)
@@ -51,30 +67,27 @@ func (bl *BotClient) Id() uint64 {
 	return bl.id
 }
 
+// AccountId returns zero because bots have no accounts
+func (bl *BotClient) AccountId() uint64 {
+	return 0
+}
+
+// Rating returns zero because bots are not rated
+func (bl *BotClient) Rating() int {
+	return 0
+}
+
 func (bl *BotClient) sendGameAction(playerActionName string, actionData interface{}) {
-	game := bl.room.game
-	if game.status != GameStatusPlaying {
-		log.Printf("BOT: Cannot send game action - wrong status of game = %s", game.status)
-		return
-	}
-	var player *Player
-	for _, gamePlayer := range game.players {
-		if gamePlayer.client.Id() == bl.Id() {
-			player = gamePlayer
-			break
-		}
-	}
-	if player == nil {
-		return
-	}
-	playerAction := &PlayerAction{Name: playerActionName, Data: actionData, player: player}
+	playerAction := &PlayerAction{Name: playerActionName, Data: actionData}
 	bl.outgoingActions <- playerAction
 }
+
+// Sends moves of the bot to the lobby, the game of the room is changed by the lobby only
 func (bl *BotClient) sendingActionsToGame() {
 	for {
 		select {
 		case playerAction := <-bl.outgoingActions:
-			bl.room.game.playerActions <- playerAction
+			bl.room.lobby.botActions <- &BotAction{bot: bl, action: playerAction}
 		}
 	}
 }
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BotSearchSettings limits information-set Monte Carlo tree search of a bot level
+type BotSearchSettings struct {
+	// Maximum number of simulated games for one decision
+	Iterations int
+	// Maximum time to think about one decision
+	ThinkingTime time.Duration
+}
+
+// Settings of bot levels which search moves
+var botSearchSettings = map[string]*BotSearchSettings{
+	BotLevelExpert: {Iterations: 3000, ThinkingTime: 2 * time.Second},
+}
+
+const (
+	// Balance between moves which won more and moves which were tried less
+	ismctsExploration = 0.7
+	// Protects from endless simulated games
+	ismctsMaxRolloutMoves = 1000
+	// Chance of a random move instead of the simple strategy in simulated games
+	ismctsRolloutRandomness = 0.2
+)
+
+// Searches the best move of the player in the game with hidden cards.
+// Each iteration deals hidden cards randomly so that they fit public knowledge
+// and plays the simulated game to the end.
+type ismctsSearch struct {
+	// Hands of other players contain only known cards
+	state *GameState
+	// Number of cards of each player which are not known
+	hiddenCardsNums []int
+	unknownCards    []*Card
+	deckSize        int
+	trumpCard       *Card
+	playerIndex     int
+	settings        *BotSearchSettings
+	rng             *rand.Rand
+}
+
+// Result of the search for the state with the version
+type ismctsDecision struct {
+	action       GameAction
+	stateVersion int
+}
+
+type ismctsNode struct {
+	action       GameAction
+	parent       *ismctsNode
+	children     []*ismctsNode
+	visits       int
+	availability int
+	reward       float64
+}
+
+// Creates search from the state of the game seen by the bot. Data is copied, so the search can run in other goroutine.
+func newIsmctsSearch(b *Bot, settings *BotSearchSettings) *ismctsSearch {
+	gsi := b.gameStateInfo
+	playersNum := len(gsi.HandsSizes)
+	state := &GameState{
+		rules:          b.gameRules,
+		players:        make([]*PlayerState, playersNum),
+		deck:           &Deck{cards: make([]*Card, 0)},
+		trumpSuit:      gsi.TrumpCard.Suit,
+		trumpCard:      gsi.TrumpCard,
+		attackerIndex:  gsi.AttackerIndex,
+		defenderIndex:  gsi.DefenderIndex,
+		battleground:   append([]*Card{}, gsi.Battleground...),
+		defendingCards: make(map[int]*Card, len(gsi.DefendingCards)),
+		defenderPickUp: gsi.DefenderPickUp,
+		roundsPlayed:   b.roundsPlayed,
+		finishingOrder: make([][]int, 0),
+		loserIndex:     -1,
+		loserTeam:      TeamNone,
+	}
+	for i, card := range gsi.DefendingCards {
+		state.defendingCards[i] = card
+	}
+	search := &ismctsSearch{
+		state:           state,
+		hiddenCardsNums: make([]int, playersNum),
+		deckSize:        gsi.DeckSize,
+		trumpCard:       gsi.TrumpCard,
+		playerIndex:     b.yourPlayerIndex,
+		settings:        settings,
+		rng:             rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for i := 0; i < playersNum; i++ {
+		player := &PlayerState{
+			cards:       make([]*Card, 0),
+			isActive:    gsi.HandsSizes[i] > 0 || gsi.DeckSize > 0 || i == gsi.AttackerIndex || i == gsi.DefenderIndex,
+			isCompleted: gsi.CompletedPlayers[i],
+		}
+		if i < len(b.players) {
+			player.team = b.players[i].Team
+		}
+		state.players[i] = player
+		if i == b.yourPlayerIndex {
+			player.cards = append(player.cards, gsi.YourHand...)
+			continue
+		}
+		for card := range b.memory.playersCards[i] {
+			if len(player.cards) < gsi.HandsSizes[i] {
+				knownCard := card
+				player.cards = append(player.cards, &knownCard)
+			}
+		}
+		search.hiddenCardsNums[i] = gsi.HandsSizes[i] - len(player.cards)
+	}
+	search.unknownCards = b.memory.getUnknownCards(gsi, b.gameRules.DeckSize)
+
+	return search
+}
+
+// Deals hidden cards randomly to other players and to the deck
+func (s *ismctsSearch) determinize() *GameState {
+	state := s.state.clone()
+	cards := append([]*Card{}, s.unknownCards...)
+	s.rng.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+	for playerIndex, hiddenCardsNum := range s.hiddenCardsNums {
+		player := state.players[playerIndex]
+		for i := 0; i < hiddenCardsNum && len(cards) > 0; i++ {
+			player.cards = append(player.cards, cards[len(cards)-1])
+			cards = cards[:len(cards)-1]
+		}
+	}
+	if s.deckSize > 0 {
+		// The trump card is at the bottom of the deck
+		state.deck.cards = append(state.deck.cards, s.trumpCard)
+		for len(state.deck.cards) < s.deckSize && len(cards) > 0 {
+			state.deck.cards = append(state.deck.cards, cards[len(cards)-1])
+			cards = cards[:len(cards)-1]
+		}
+	}
+	return state
+}
+
+// Returns moves which the player can make now
+func (s *ismctsSearch) getLegalActions() []GameAction {
+	if s.state.getWaitingPlayerIndex() != s.playerIndex {
+		return nil
+	}
+	return s.state.getLegalActions(s.playerIndex)
+}
+
+// Searches until the number of iterations or the thinking time is over and returns the most visited move
+func (s *ismctsSearch) run() GameAction {
+	root := &ismctsNode{}
+	deadline := time.Now().Add(s.settings.ThinkingTime)
+	for i := 0; i < s.settings.Iterations && time.Now().Before(deadline); i++ {
+		s.iterate(root)
+	}
+
+	var best *ismctsNode
+	for _, child := range root.children {
+		if best == nil || child.visits > best.visits {
+			best = child
+		}
+	}
+	if best == nil {
+		return s.getLegalActions()[0]
+	}
+	return best.action
+}
+
+func (s *ismctsSearch) iterate(root *ismctsNode) {
+	state := s.determinize()
+	node := root
+
+	// Selection and expansion of moves which are possible in this determinization
+	for !state.isEnded {
+		actions := state.getLegalActions(state.getWaitingPlayerIndex())
+		if len(actions) == 0 {
+			break
+		}
+		untriedActions := make([]GameAction, 0)
+		for _, action := range actions {
+			if node.getChild(action) == nil {
+				untriedActions = append(untriedActions, action)
+			}
+		}
+		if len(untriedActions) > 0 {
+			action := untriedActions[s.rng.Intn(len(untriedActions))]
+			child := &ismctsNode{action: action, parent: node, availability: 1}
+			node.children = append(node.children, child)
+			state.play(action)
+			node = child
+			break
+		}
+		node = node.selectChild(actions)
+		state.play(node.action)
+	}
+
+	s.playRandomly(state)
+
+	for ; node.parent != nil; node = node.parent {
+		node.visits++
+		node.reward += s.getReward(state, node.action.PlayerIndex)
+	}
+	root.visits++
+}
+
+// Plays the simulated game to the end. Players use the lowest cards, throw in only non-trump cards
+// and pick up when they cannot beat, sometimes they make random moves.
+func (s *ismctsSearch) playRandomly(state *GameState) {
+	for i := 0; i < ismctsMaxRolloutMoves && !state.isEnded; i++ {
+		actions := state.getLegalActions(state.getWaitingPlayerIndex())
+		if len(actions) == 0 {
+			return
+		}
+		if s.rng.Float64() < ismctsRolloutRandomness {
+			state.play(actions[s.rng.Intn(len(actions))])
+			continue
+		}
+
+		var chosen *GameAction
+		var chosenCard *Card
+		for j, action := range actions {
+			isCardAction := action.Name == PlayerActionNameAttack || action.Name == PlayerActionNameDefend
+			if !isCardAction {
+				continue
+			}
+			card, _ := getGameActionCards(action)
+			if action.Name == PlayerActionNameAttack && len(state.battleground) > 0 && card.Suit == state.trumpSuit {
+				continue
+			}
+			if chosen == nil || getCardCost(state.trumpSuit, card) < getCardCost(state.trumpSuit, chosenCard) {
+				chosen = &actions[j]
+				chosenCard = card
+			}
+		}
+		if chosen == nil {
+			// Pick up or complete
+			chosen = &actions[len(actions)-1]
+		}
+		state.play(*chosen)
+	}
+}
+
+// Returns 1 when the player did not lose the simulated game and 0.5 when the game was not finished
+func (s *ismctsSearch) getReward(state *GameState, playerIndex int) float64 {
+	if !state.isEnded {
+		return 0.5
+	}
+	if state.isLoser(playerIndex) {
+		return 0
+	}
+	return 1
+}
+
+// Trumps cost more than cards of other suits
+func getCardCost(trumpSuit string, card *Card) int {
+	if card.Suit == trumpSuit {
+		return card.getValueIndex() + len(cardValues)
+	}
+	return card.getValueIndex()
+}
+
+func (n *ismctsNode) getChild(action GameAction) *ismctsNode {
+	for _, child := range n.children {
+		if isSameGameAction(child.action, action) {
+			return child
+		}
+	}
+	return nil
+}
+
+// Selects the child with the best upper confidence bound among children with legal moves
+func (n *ismctsNode) selectChild(actions []GameAction) *ismctsNode {
+	var best *ismctsNode
+	bestScore := math.Inf(-1)
+	for _, action := range actions {
+		child := n.getChild(action)
+		child.availability++
+		score := child.reward/float64(child.visits) +
+			ismctsExploration*math.Sqrt(math.Log(float64(child.availability))/float64(child.visits))
+		if score > bestScore {
+			best = child
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// Checks if actions are the same move. Cards are compared by values because they can come from different determinizations.
+func isSameGameAction(a GameAction, b GameAction) bool {
+	if a.PlayerIndex != b.PlayerIndex || a.Name != b.Name {
+		return false
+	}
+	aCard, aAttackingCard := getGameActionCards(a)
+	bCard, bAttackingCard := getGameActionCards(b)
+	return isSameCard(aCard, bCard) && isSameCard(aAttackingCard, bAttackingCard)
+}
+
+// Returns the card played by the action and the attacking card which it beats
+func getGameActionCards(action GameAction) (card *Card, attackingCard *Card) {
+	switch data := action.Data.(type) {
+	case AttackActionData:
+		return data.Card, nil
+	case DefendActionData:
+		return data.DefendingCard, data.AttackingCard
+	case TransferActionData:
+		return data.Card, nil
+	}
+	return nil, nil
+}
+
+func isSameCard(a *Card, b *Card) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.equals(b)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsmctsSearchFindsWinningAttack(t *testing.T) {
+	// Attack with the low card lets the opponent get rid of the last card,
+	// while the trump ace has to be picked up
+	state := newTestGameState(
+		[]*Card{{"6", "♥"}, {"A", "♠"}},
+		[]*Card{{"7", "♥"}},
+	)
+	state.roundsPlayed = 1
+	search := &ismctsSearch{
+		state:           state,
+		hiddenCardsNums: make([]int, 2),
+		unknownCards:    make([]*Card, 0),
+		trumpCard:       &Card{"6", "♠"},
+		playerIndex:     0,
+		settings:        &BotSearchSettings{Iterations: 300, ThinkingTime: time.Second},
+		rng:             rand.New(rand.NewSource(1)),
+	}
+
+	assert.Len(t, search.getLegalActions(), 2)
+	assert.True(t, isSameGameAction(newAttackAction(0, &Card{"A", "♠"}), search.run()))
+}
+
+func TestIsmctsSearchDealsHiddenCards(t *testing.T) {
+	search := &ismctsSearch{
+		state: newTestGameState(
+			[]*Card{{"6", "♥"}},
+			[]*Card{{"7", "♥"}},
+		),
+		hiddenCardsNums: []int{0, 2},
+		unknownCards:    []*Card{{"8", "♥"}, {"9", "♥"}, {"10", "♥"}, {"J", "♥"}},
+		deckSize:        3,
+		trumpCard:       &Card{"6", "♠"},
+		rng:             rand.New(rand.NewSource(1)),
+	}
+
+	state := search.determinize()
+	assert := assert.New(t)
+	assert.Len(state.players[1].cards, 3)
+	assert.Equal(&Card{"7", "♥"}, state.players[1].cards[0])
+	assert.Len(state.deck.cards, 3)
+	assert.Equal(&Card{"6", "♠"}, state.deck.cards[0])
+	assert.Len(search.state.players[1].cards, 1)
+}
+
+func TestIsSameGameActionComparesCards(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isSameGameAction(newAttackAction(0, &Card{"7", "♥"}), newAttackAction(0, &Card{"7", "♥"})))
+	assert.False(isSameGameAction(newAttackAction(0, &Card{"7", "♥"}), newAttackAction(1, &Card{"7", "♥"})))
+	assert.False(isSameGameAction(
+		newDefendAction(1, &Card{"7", "♥"}, &Card{"9", "♥"}),
+		newDefendAction(1, &Card{"8", "♥"}, &Card{"9", "♥"}),
+	))
+	assert.True(isSameGameAction(newPickUpAction(1), newPickUpAction(1)))
+}
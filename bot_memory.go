@@ -0,0 +1,111 @@
+package main
+
+// Remembers public information about cards: which cards were discarded
+// and which cards are known to be in hands of players
+type botMemory struct {
+	discardedCards map[Card]bool
+	// Cards which players picked up from the table or got as the trump card at the end of the deck
+	playersCards map[int]map[Card]bool
+}
+
+func newBotMemory() *botMemory {
+	return &botMemory{
+		discardedCards: make(map[Card]bool, 0),
+		playersCards:   make(map[int]map[Card]bool, 0),
+	}
+}
+
+func (m *botMemory) addPlayerCard(playerIndex int, card *Card) {
+	if _, ok := m.playersCards[playerIndex]; !ok {
+		m.playersCards[playerIndex] = make(map[Card]bool, 0)
+	}
+	m.playersCards[playerIndex][*card] = true
+}
+
+// Known card leaves the hand when the player puts it on the table
+func (m *botMemory) onCardPlayed(playerIndex int, card *Card) {
+	if card == nil {
+		return
+	}
+	delete(m.playersCards[playerIndex], *card)
+}
+
+// Cards on the table go to the defender who picked them up or to discard pile
+func (m *botMemory) onRoundEnded(table *GameStateInfo, wasAttackSuccessful bool) {
+	cards := make([]*Card, 0)
+	cards = append(cards, table.Battleground...)
+	for _, card := range table.DefendingCards {
+		cards = append(cards, card)
+	}
+	for _, card := range cards {
+		if wasAttackSuccessful {
+			m.addPlayerCard(table.DefenderIndex, card)
+		} else {
+			m.discardedCards[*card] = true
+		}
+	}
+}
+
+// Returns number of cards in hands of the player which are known
+func (m *botMemory) getKnownCardsNum(playerIndex int) int {
+	return len(m.playersCards[playerIndex])
+}
+
+// Returns cards which can be in hands of other players or in the deck and nobody has seen them
+func (m *botMemory) getUnknownCards(gsi *GameStateInfo, deckSize int) []*Card {
+	seenCards := make(map[Card]bool, 0)
+	for card := range m.discardedCards {
+		seenCards[card] = true
+	}
+	for _, cards := range m.playersCards {
+		for card := range cards {
+			seenCards[card] = true
+		}
+	}
+	for _, card := range gsi.YourHand {
+		seenCards[*card] = true
+	}
+	for _, card := range gsi.Battleground {
+		seenCards[*card] = true
+	}
+	for _, card := range gsi.DefendingCards {
+		seenCards[*card] = true
+	}
+	if gsi.DeckSize > 0 && gsi.TrumpCard != nil {
+		// Trump card lies face up at the bottom of the deck
+		seenCards[*gsi.TrumpCard] = true
+	}
+
+	unknownCards := make([]*Card, 0)
+	for _, card := range newDeck(deckSize).cards {
+		if !seenCards[*card] {
+			unknownCards = append(unknownCards, card)
+		}
+	}
+
+	return unknownCards
+}
+
+// Checks whether the player has or may have a card to beat the attacking card
+func (m *botMemory) canPlayerBeat(playerIndex int, handSize int, attackingCard *Card, unknownCards []*Card, trumpSuit string) bool {
+	for card := range m.playersCards[playerIndex] {
+		knownCard := card
+		if cardBeats(&knownCard, attackingCard, trumpSuit) {
+			return true
+		}
+	}
+	if handSize <= m.getKnownCardsNum(playerIndex) {
+		return false
+	}
+	for _, card := range unknownCards {
+		if cardBeats(card, attackingCard, trumpSuit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func cardBeats(defendingCard *Card, attackingCard *Card, trumpSuit string) bool {
+	return defendingCard.Suit == trumpSuit && attackingCard.Suit != trumpSuit || defendingCard.gt(attackingCard)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBotMemoryRemembersPickedUpCards(t *testing.T) {
+	memory := newBotMemory()
+	memory.onRoundEnded(&GameStateInfo{
+		Battleground:   []*Card{{"7", "♥"}, {"7", "♣"}},
+		DefendingCards: map[int]*Card{0: {"9", "♥"}},
+		DefenderIndex:  1,
+	}, true)
+	memory.onCardPlayed(1, &Card{"9", "♥"})
+	memory.onRoundEnded(&GameStateInfo{
+		Battleground:   []*Card{{"8", "♠"}},
+		DefendingCards: map[int]*Card{0: {"10", "♠"}},
+		DefenderIndex:  0,
+	}, false)
+
+	assert := assert.New(t)
+	assert.Equal(map[Card]bool{{"7", "♥"}: true, {"7", "♣"}: true}, memory.playersCards[1])
+	assert.Equal(map[Card]bool{{"8", "♠"}: true, {"10", "♠"}: true}, memory.discardedCards)
+}
+
+func TestBotMemoryCanPlayerBeat(t *testing.T) {
+	memory := newBotMemory()
+	memory.addPlayerCard(1, &Card{"6", "♦"})
+	gsi := &GameStateInfo{
+		YourHand:  []*Card{{"A", "♥"}, {"K", "♥"}, {"Q", "♥"}, {"J", "♥"}, {"10", "♥"}, {"9", "♥"}},
+		TrumpCard: &Card{"6", "♠"},
+		DeckSize:  0,
+	}
+	unknownCards := memory.getUnknownCards(gsi, 36)
+
+	assert := assert.New(t)
+	assert.Len(unknownCards, 29)
+	// The only card of the player is known
+	assert.False(memory.canPlayerBeat(1, 1, &Card{"7", "♥"}, unknownCards, "♠"))
+	assert.True(memory.canPlayerBeat(1, 1, &Card{"6", "♣"}, unknownCards, "♦"))
+	// Unknown card of the player can be a trump
+	assert.True(memory.canPlayerBeat(1, 2, &Card{"7", "♥"}, unknownCards, "♠"))
+}
+
+func TestBotRemembersTrumpCardOwner(t *testing.T) {
+	bot := &Bot{yourPlayerIndex: 2, memory: newBotMemory()}
+	before := &GameStateInfo{
+		HandsSizes:    []int{5, 4, 6},
+		DeckSize:      2,
+		TrumpCard:     &Card{"6", "♠"},
+		Battleground:  []*Card{{"7", "♥"}},
+		AttackerIndex: 0,
+		DefenderIndex: 1,
+	}
+	after := &GameStateInfo{
+		HandsSizes: []int{6, 5, 6},
+		DeckSize:   0,
+	}
+	bot.rememberTrumpCardOwner(before, after, true)
+
+	assert.Equal(t, map[Card]bool{{"6", "♠"}: true}, bot.memory.playersCards[0])
+	assert.Empty(t, bot.memory.playersCards[1])
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// BotPolicyVersion is increased when keys of decisions are changed, old policies have to be trained again
+const BotPolicyVersion = 1
+
+// BotPolicy contains frequencies of decisions which human players made in saved games.
+// Each option of a decision is described by a key like "throwIn/deckFew/plain.low.lowest.single",
+// trained bots choose the option which players chose most often when it was available.
+type BotPolicy struct {
+	Version      int                         `json:"version"`
+	GamesNum     int                         `json:"gamesNum"`
+	DecisionsNum int                         `json:"decisionsNum"`
+	Options      map[string]*BotPolicyOption `json:"options"`
+}
+
+// BotPolicyOption counts how many times the option was available and how many times it was chosen
+type BotPolicyOption struct {
+	Available int `json:"available"`
+	Chosen    int `json:"chosen"`
+}
+
+func newBotPolicy() *BotPolicy {
+	return &BotPolicy{
+		Version: BotPolicyVersion,
+		Options: make(map[string]*BotPolicyOption, 0),
+	}
+}
+
+// Loads the policy from the file, returns nil without error when the file does not exist
+func loadBotPolicy(path string) (*BotPolicy, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy := newBotPolicy()
+	if err := json.Unmarshal(contents, policy); err != nil {
+		return nil, fmt.Errorf("cannot parse bot policy file %s: %s", path, err)
+	}
+	if policy.Version != BotPolicyVersion {
+		return nil, fmt.Errorf("bot policy file %s has version %d, train it again", path, policy.Version)
+	}
+	return policy, nil
+}
+
+func (p *BotPolicy) save(path string) error {
+	return writeJsonFile(path, p)
+}
+
+// Counts the decision: every option was available and one of them was chosen
+func (p *BotPolicy) addDecision(optionsKeys []string, chosenKey string) {
+	for _, key := range optionsKeys {
+		option, ok := p.Options[key]
+		if !ok {
+			option = &BotPolicyOption{}
+			p.Options[key] = option
+		}
+		option.Available++
+		if key == chosenKey {
+			option.Chosen++
+		}
+	}
+	p.DecisionsNum++
+}
+
+// Returns the share of decisions where the option was chosen, unknown options get one half
+func (p *BotPolicy) getScore(key string) float64 {
+	option, ok := p.Options[key]
+	if !ok {
+		return 0.5
+	}
+	return (float64(option.Chosen) + 1) / (float64(option.Available) + 2)
+}
+
+// Returns keys of the legal actions of the player, actions which are not choices of the policy get empty keys.
+// Several actions can have the same key, e.g. two low cards of different suits.
+func getBotPolicyActionsKeys(state *GameState, actions []GameAction) []string {
+	keys := make([]string, len(actions))
+	for i, action := range actions {
+		keys[i] = getBotPolicyActionKey(state, action)
+	}
+	return keys
+}
+
+// Describes the action by the situation and features of its card, so decisions of different games can be counted together
+func getBotPolicyActionKey(state *GameState, action GameAction) string {
+	phase := getBotPolicyDeckPhase(len(state.deck.cards))
+	table := fmt.Sprintf("table%d", minInt(len(state.battleground), 3))
+	card, _ := getGameActionCards(action)
+	switch action.Name {
+	case PlayerActionNameAttack:
+		situation := "lead"
+		if len(state.battleground) > 0 {
+			situation = "throwIn"
+		}
+		return strings.Join([]string{situation, phase, getBotPolicyCardFeatures(state, action.PlayerIndex, card)}, "/")
+	case PlayerActionNameDefend:
+		return strings.Join([]string{"defend", phase, getBotPolicyCardFeatures(state, action.PlayerIndex, card)}, "/")
+	case PlayerActionNameTransfer:
+		return strings.Join([]string{"transfer", phase, getBotPolicyCardFeatures(state, action.PlayerIndex, card)}, "/")
+	case PlayerActionNamePickUp:
+		return strings.Join([]string{"defend", phase, table, "pickUp"}, "/")
+	case PlayerActionNameComplete:
+		if action.PlayerIndex == state.defenderIndex {
+			// The defender completes when everything is beaten, it is not a choice
+			return ""
+		}
+		return strings.Join([]string{"throwIn", phase, table, "stop"}, "/")
+	}
+	return ""
+}
+
+func getBotPolicyDeckPhase(deckSize int) string {
+	switch {
+	case deckSize == 0:
+		return "deckEmpty"
+	case deckSize <= 6:
+		return "deckFew"
+	}
+	return "deckMany"
+}
+
+// Features of the card in the hand: trump or not, low, middle or high value,
+// the lowest card of its kind or not and whether the hand has cards of the same value
+func getBotPolicyCardFeatures(state *GameState, playerIndex int, card *Card) string {
+	isTrump := card.Suit == state.trumpSuit
+	kind := "plain"
+	if isTrump {
+		kind = "trump"
+	}
+
+	valuesNum := len(getDeckValues(state.rules.DeckSize))
+	valueGroup := "low"
+	switch valueIndex := card.getValueIndexInDeck(state.rules.DeckSize); {
+	case valueIndex >= valuesNum*2/3:
+		valueGroup = "high"
+	case valueIndex >= valuesNum/3:
+		valueGroup = "middle"
+	}
+
+	rank := "lowest"
+	sameValue := "single"
+	for _, c := range state.players[playerIndex].cards {
+		if c.equals(card) {
+			continue
+		}
+		if (c.Suit == state.trumpSuit) == isTrump && c.getValueIndex() < card.getValueIndex() {
+			rank = "higher"
+		}
+		if c.Value == card.Value {
+			sameValue = "same"
+		}
+	}
+
+	return strings.Join([]string{kind, valueGroup, rank, sameValue}, ".")
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Chooses the legal action which players chose most often in the same situation.
+// Actions which were never seen are compared by cost of their cards, so the lowest card is played.
+func (p *BotPolicy) chooseAction(state *GameState, actions []GameAction) GameAction {
+	keys := getBotPolicyActionsKeys(state, actions)
+	bestIndex := 0
+	bestScore := -1.0
+	bestCost := 0
+	for i, action := range actions {
+		if keys[i] == "" {
+			continue
+		}
+		score := p.getScore(keys[i])
+		cost := getBotPolicyActionCost(state, action)
+		if score > bestScore || (score == bestScore && cost < bestCost) {
+			bestIndex = i
+			bestScore = score
+			bestCost = cost
+		}
+	}
+	return actions[bestIndex]
+}
+
+// Cards cost by their values and trumps cost more than other cards, moves without cards cost the most
+func getBotPolicyActionCost(state *GameState, action GameAction) int {
+	card, _ := getGameActionCards(action)
+	if card == nil {
+		return len(cardValues) * 2
+	}
+	return getCardCost(state.trumpSuit, card)
+}
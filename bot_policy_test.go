@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Plays a short game where Alice leads with the low card, beats the attack and wins
+func writeTestPolicyGameLog(logger BufferedGameLogger) *Game {
+	game := newTestGame(
+		[]*Card{{"6", "♥"}, {"A", "♣"}},
+		[]*Card{{"7", "♥"}, {"8", "♣"}, {"9", "♠"}},
+	)
+	game.players[0].Name = "Alice"
+	game.players[1].Name = "Bob"
+	game.gameLogger = logger
+	game.deckCommitment = &DeckCommitment{}
+
+	logger.LogGameBegins(game)
+	game.applyAction(newAttackAction(0, &Card{"6", "♥"}))
+	game.applyAction(newDefendAction(1, &Card{"6", "♥"}, &Card{"7", "♥"}))
+	game.applyAction(newCompleteAction(0))
+	game.applyAction(newCompleteAction(1))
+	game.applyAction(newAttackAction(1, &Card{"8", "♣"}))
+	game.applyAction(newDefendAction(0, &Card{"8", "♣"}, &Card{"A", "♣"}))
+	game.applyAction(newCompleteAction(1))
+	game.applyAction(newCompleteAction(0))
+	logger.Wait()
+
+	return game
+}
+
+func TestTrainBotPolicy(t *testing.T) {
+	for _, format := range []string{GameLogFormatText, GameLogFormatJson} {
+		dir := newTestGameLogDir(t)
+		var logger BufferedGameLogger = newTestGameFileLogger(t, dir)
+		if format == GameLogFormatJson {
+			logger = NewGameJsonLogger(dir, func(err error) {
+				t.Errorf("Cannot write log: %s", err)
+			})
+		}
+		game := writeTestPolicyGameLog(logger)
+		assert := assert.New(t)
+		assert.Equal(GameEndReasonLoser, game.state.endReason, format)
+
+		policy, err := trainBotPolicy(dir)
+		assert.Nil(err, format)
+		assert.Equal(1, policy.GamesNum, format)
+		// Decisions of Bob are not learned because Bob lost the game
+		assert.Equal(2, policy.DecisionsNum, format)
+		assert.Equal(&BotPolicyOption{Available: 1, Chosen: 1}, policy.Options["lead/deckEmpty/plain.low.lowest.single"], format)
+		assert.Equal(&BotPolicyOption{Available: 1, Chosen: 0}, policy.Options["lead/deckEmpty/plain.high.higher.single"], format)
+		assert.Equal(&BotPolicyOption{Available: 1, Chosen: 1}, policy.Options["defend/deckEmpty/plain.high.lowest.single"], format)
+		assert.Equal(&BotPolicyOption{Available: 1, Chosen: 0}, policy.Options["defend/deckEmpty/table1/pickUp"], format)
+	}
+}
+
+func TestBotPolicyChoosesMostFrequentAction(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"6", "♥"}, {"A", "♣"}},
+		[]*Card{{"7", "♥"}},
+	)
+	actions := state.getLegalActions(0)
+
+	policy := newBotPolicy()
+	assert := assert.New(t)
+	assert.Equal(newAttackAction(0, &Card{"6", "♥"}), policy.chooseAction(state, actions), "the lowest card without data")
+
+	for i := 0; i < 3; i++ {
+		policy.addDecision([]string{
+			"lead/deckEmpty/plain.low.lowest.single",
+			"lead/deckEmpty/plain.high.higher.single",
+		}, "lead/deckEmpty/plain.high.higher.single")
+	}
+	assert.Equal(newAttackAction(0, &Card{"A", "♣"}), policy.chooseAction(state, actions))
+}
+
+func TestSaveAndLoadBotPolicy(t *testing.T) {
+	path := filepath.Join(newTestGameLogDir(t), "bot_policy.json")
+	assert := assert.New(t)
+
+	policy, err := loadBotPolicy(path)
+	assert.Nil(err)
+	assert.Nil(policy)
+
+	policy = newBotPolicy()
+	policy.addDecision([]string{"defend/deckMany/table1/pickUp", "defend/deckMany/trump.low.lowest.single"}, "defend/deckMany/table1/pickUp")
+	assert.Nil(policy.save(path))
+
+	loadedPolicy, err := loadBotPolicy(path)
+	assert.Nil(err)
+	assert.Equal(policy, loadedPolicy)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Names of entries of logs which are moves of players
+var gameHistoryActionNames = map[string]string{
+	"Attack":   PlayerActionNameAttack,
+	"Defend":   PlayerActionNameDefend,
+	"Transfer": PlayerActionNameTransfer,
+	"PickUp":   PlayerActionNamePickUp,
+	"Complete": PlayerActionNameComplete,
+}
+
+// Reads all games from the dir of logs and counts decisions of human players who did not lose.
+// Logs which cannot be read are skipped.
+func trainBotPolicy(dir string) (*BotPolicy, error) {
+	policy := newBotPolicy()
+	err := walkGameLogs(dir, "", func(gameId string) (bool, error) {
+		history, err := readGameHistory(dir, gameId)
+		if os.IsNotExist(err) {
+			// Game is not finished yet
+			return true, nil
+		}
+		if err != nil {
+			log.Printf("Cannot read game %s: %s", gameId, err)
+			return true, nil
+		}
+		policy.addGame(history)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Replays moves of the game by rules and counts decisions of humans. Only games which were played
+// to the end are used. Replay stops when the state does not match the log, e.g. when the log was written by older rules.
+func (p *BotPolicy) addGame(history *GameHistory) {
+	if !history.IsEnded || (history.Reason != GameEndReasonLoser && history.Reason != GameEndReasonDraw) {
+		return
+	}
+
+	var state *GameState
+	for _, entry := range history.Entries {
+		if state == nil {
+			if entry.Name == "Game begins" && entry.State != nil {
+				state = newGameStateFromHistory(history, entry.State)
+			}
+			continue
+		}
+
+		switch entry.Name {
+		case "Player left":
+			playerIndex, _ := strconv.Atoi(entry.Params["player"])
+			if !state.isPlayerIndexValid(playerIndex) {
+				return
+			}
+			state, _ = state.leave(playerIndex, entry.Params["isAfk"] == "true")
+			continue
+		case "Round ends":
+			if !isGameStateMatchingHistory(state, entry.State) {
+				return
+			}
+			continue
+		}
+		if _, ok := gameHistoryActionNames[entry.Name]; !ok {
+			continue
+		}
+
+		action, ok := findGameHistoryAction(state, entry)
+		if !ok {
+			return
+		}
+		if isLearnedGameHistoryPlayer(history, action.PlayerIndex) {
+			p.addStateDecision(state, action)
+		}
+
+		roundsPlayed := state.roundsPlayed
+		if _, err := state.play(action); err != nil {
+			return
+		}
+		// The move which ends the round is logged with cards of the round on the table
+		if state.roundsPlayed == roundsPlayed && !isGameStateMatchingHistory(state, entry.State) {
+			return
+		}
+	}
+	if state != nil {
+		p.GamesNum++
+	}
+}
+
+// Counts the move of the player if the player had other options
+func (p *BotPolicy) addStateDecision(state *GameState, action GameAction) {
+	chosenKey := getBotPolicyActionKey(state, action)
+	if chosenKey == "" {
+		return
+	}
+	optionsKeys := make([]string, 0)
+	isAdded := make(map[string]bool, 0)
+	for _, key := range getBotPolicyActionsKeys(state, state.getLegalActions(action.PlayerIndex)) {
+		if key != "" && !isAdded[key] {
+			optionsKeys = append(optionsKeys, key)
+			isAdded[key] = true
+		}
+	}
+	if len(optionsKeys) < 2 {
+		return
+	}
+	p.addDecision(optionsKeys, chosenKey)
+}
+
+// Creates the state from cards which were dealt at the beginning of the game
+func newGameStateFromHistory(history *GameHistory, historyState *GameHistoryState) *GameState {
+	players := make([]*Player, 0)
+	for i, cards := range historyState.PlayersCards {
+		player := &Player{IsActive: len(cards) > 0}
+		if i < len(history.Players) {
+			player.Team = history.Players[i].Team
+		}
+		players = append(players, player)
+	}
+	state := newGameState(getRulesFromMap(history.Rules), players)
+	for i, cards := range historyState.PlayersCards {
+		state.players[i].cards = append(state.players[i].cards, cards...)
+	}
+	state.deck.cards = append(state.deck.cards, historyState.Deck...)
+	state.trumpCard = historyState.Trump
+	if state.trumpCard != nil {
+		state.trumpSuit = state.trumpCard.Suit
+	}
+	state.attackerIndex = historyState.AttackerIndex
+	state.defenderIndex = historyState.DefenderIndex
+
+	return state
+}
+
+// Returns the move of the entry. Text logs have no actors of moves,
+// so the actor is the player who can make the move, the player whom the game waits for goes first.
+func findGameHistoryAction(state *GameState, entry *GameHistoryEntry) (GameAction, bool) {
+	action := GameAction{Name: gameHistoryActionNames[entry.Name]}
+	switch action.Name {
+	case "":
+		return action, false
+	case PlayerActionNameAttack:
+		action.Data = AttackActionData{Card: getEntryCard(entry, "card")}
+	case PlayerActionNameDefend:
+		action.Data = DefendActionData{
+			AttackingCard: getEntryCard(entry, "attackingCard"),
+			DefendingCard: getEntryCard(entry, "defendingCard"),
+		}
+	case PlayerActionNameTransfer:
+		action.Data = TransferActionData{Card: getEntryCard(entry, "card")}
+	}
+
+	playersIndexes := []int{state.getWaitingPlayerIndex()}
+	if entry.ActorIndex != nil {
+		playersIndexes = []int{*entry.ActorIndex}
+	} else {
+		for i := range state.players {
+			playersIndexes = append(playersIndexes, i)
+		}
+	}
+	for _, playerIndex := range playersIndexes {
+		action.PlayerIndex = playerIndex
+		for _, legalAction := range state.getLegalActions(playerIndex) {
+			if isSameGameAction(action, legalAction) {
+				return legalAction, true
+			}
+		}
+	}
+	return action, false
+}
+
+// Checks hands and the table of the replayed state with the logged state
+func isGameStateMatchingHistory(state *GameState, historyState *GameHistoryState) bool {
+	if historyState == nil {
+		return true
+	}
+	if len(state.deck.cards) != len(historyState.Deck) || len(state.battleground) != len(historyState.Battleground) {
+		return false
+	}
+	for i, p := range state.players {
+		if i < len(historyState.PlayersCards) && len(p.cards) != len(historyState.PlayersCards[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Decisions of bots and of players who lost the game are not learned
+func isLearnedGameHistoryPlayer(history *GameHistory, playerIndex int) bool {
+	if playerIndex < 0 || playerIndex >= len(history.Players) || history.Players[playerIndex].IsBot {
+		return false
+	}
+	if !history.HasLoser {
+		return true
+	}
+	lastPlace := 0
+	playerPlace := 0
+	for _, placing := range history.Placings {
+		if placing.Place > lastPlace {
+			lastPlace = placing.Place
+		}
+		if placing.PlayerIndex == playerIndex {
+			playerPlace = placing.Place
+		}
+	}
+	return playerIndex != history.LoserIndex && (playerPlace == 0 || playerPlace < lastPlace)
+}
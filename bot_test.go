@@ -62,3 +62,39 @@ func TestFindLowestCard4(t *testing.T) {
 		t.Errorf("findLowestCard expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestGetAvailableCardsForTransfer(t *testing.T) {
+	bot := &Bot{gameStateInfo: &GameStateInfo{
+		TrumpCard:    &Card{"9", "♦"},
+		Battleground: []*Card{{"7", "♥"}},
+		YourHand:     []*Card{{"7", "♣"}, {"8", "♥"}, {"7", "♦"}},
+	}}
+
+	got := bot.getAvailableCardsForTransfer()
+	if len(got) != 2 {
+		t.Fatalf("getAvailableCardsForTransfer expected 2 cards, got: %v", len(got))
+	}
+	expected := &Card{"7", "♣"}
+	lowest := bot.findLowestCard(got)
+	if !lowest.equals(expected) {
+		t.Errorf("getAvailableCardsForTransfer expected lowest: %v, got: %v", expected, lowest)
+	}
+}
+
+func TestFindLowestCardOfScarceSuit(t *testing.T) {
+	bot := &Bot{
+		gameStateInfo: &GameStateInfo{
+			TrumpCard: &Card{"9", "♦"},
+			YourHand:  []*Card{{"7", "♥"}, {"7", "♣"}, {"6", "♦"}},
+		},
+		memory: newBotMemory(),
+	}
+	bot.memory.discardedCards[Card{"8", "♣"}] = true
+	bot.memory.discardedCards[Card{"Q", "♣"}] = true
+
+	got := bot.findLowestCardOfScarceSuit(bot.gameStateInfo.YourHand)
+	expected := &Card{"7", "♣"}
+	if !got.equals(expected) {
+		t.Errorf("findLowestCardOfScarceSuit expected: %v, got: %v", expected, got)
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 var (
-	cardValues = []string{"6", "7", "8", "9", "10", "J", "Q", "K", "A"}
+	cardValues = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
 	cardSuits  = []string{"♣", "♦", "♥", "♠"}
 )
 
@@ -21,6 +21,15 @@ func (c *Card) getValueIndex() int {
 	return index
 }
 
+// Returns index of value in the deck of given size: 0 is for the lowest value of the deck
+func (c *Card) getValueIndexInDeck(deckSize int) int {
+	index := c.getValueIndex()
+	if index < 0 {
+		return index
+	}
+	return index - (len(cardValues) - len(getDeckValues(deckSize)))
+}
+
 func (c *Card) gt(otherCard *Card) bool {
 	if c.Suit != otherCard.Suit {
 		return false
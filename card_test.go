@@ -5,12 +5,33 @@ import "testing"
 func TestGetValueIndex(t *testing.T) {
 	card := &Card{"9", "♦"}
 	got := card.getValueIndex()
-	expected := 3
+	expected := 7
 	if got != expected {
 		t.Errorf("getValueIndex expected: %v, got: %v", expected, got)
 	}
 }
 
+func TestGetValueIndexInDeck(t *testing.T) {
+	card := &Card{"9", "♦"}
+	expectedByDeckSize := map[int]int{DeckSize24: 0, DeckSize36: 3, DeckSize52: 7}
+	for deckSize, expected := range expectedByDeckSize {
+		got := card.getValueIndexInDeck(deckSize)
+		if got != expected {
+			t.Errorf("getValueIndexInDeck expected for deck %d: %v, got: %v", deckSize, expected, got)
+		}
+	}
+}
+
+func TestGtLowValues(t *testing.T) {
+	card := &Card{"10", "♦"}
+	otherCard := &Card{"2", "♦"}
+	got := card.gt(otherCard)
+	expected := true
+	if got != expected {
+		t.Errorf("TestGtLowValues expected: %v, got: %v", expected, got)
+	}
+}
+
 func TestGtFalse(t *testing.T) {
 	card := &Card{"9", "♦"}
 	otherCard := &Card{"10", "♠"}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/gorilla/websocket"
 	"log"
+	"net"
 	"net/http"
 	"time"
 )
@@ -33,6 +34,8 @@ type ClientSender interface {
 	sendMessage(message []byte)
 	Id() uint64
 	Nickname() string
+	AccountId() uint64
+	Rating() int
 }
 
 // Client represents a connected user using websockets.
@@ -49,6 +52,24 @@ type Client struct {
 	nickname string
 	id       uint64
 	room     *Room
+
+	// Id of the registered account, zero for guests
+	accountId uint64
+	rating    int
+
+	// Token to resume the session and timer which removes the client if the session is not resumed
+	sessionToken   string
+	reconnectTimer *time.Timer
+	// Client joined the lobby with a nickname or resumed a session
+	hasJoined bool
+
+	// Room where the client watches a replay of a logged game
+	replayRoom *ReplayRoom
+
+	// Address of the connection without port, logins with wrong passwords are throttled by it
+	remoteHost string
+	// Credentials of the client are being checked
+	isLoggingIn bool
 }
 
 // Nickname returns nickname of the client
@@ -61,6 +82,16 @@ func (c *Client) Id() uint64 {
 	return c.id
 }
 
+// AccountId returns id of the verified account of the client or zero for guests
+func (c *Client) AccountId() uint64 {
+	return c.accountId
+}
+
+// Rating returns rounded rating of the account or zero if the client is not rated
+func (c *Client) Rating() int {
+	return c.rating
+}
+
 func (c *Client) readLoop() {
 	defer func() {
 		c.lobby.unregister <- c
@@ -142,12 +173,21 @@ func serveWs(lobby *Lobby, w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		lobby: lobby,
-		conn:  conn,
-		send:  make(chan []byte),
+		lobby:      lobby,
+		conn:       conn,
+		send:       make(chan []byte),
+		remoteHost: getRemoteHost(r),
 	}
 	client.lobby.register <- client
 
 	go client.writeLoop()
 	go client.readLoop()
 }
+
+func getRemoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -11,6 +11,21 @@ const (
 	ClientCommandLobbySubTypeCreateRoom = "createRoom"
 	// ClientCommandLobbySubTypeJoinRoom join the room
 	ClientCommandLobbySubTypeJoinRoom = "joinRoom"
+	// ClientCommandLobbySubTypeRegister register a new account and join the lobby
+	ClientCommandLobbySubTypeRegister = "register"
+	// ClientCommandLobbySubTypeLogin log in with password or login token and join the lobby
+	ClientCommandLobbySubTypeLogin = "login"
+	// ClientCommandLobbySubTypeResume reattach a new connection to the session of a dropped connection
+	ClientCommandLobbySubTypeResume = "resume"
+
+	// ClientCommandTypeReplay namespace for commands about a replay of a logged game
+	ClientCommandTypeReplay = "replay"
+	// ClientCommandReplaySubTypeOpen open the replay room with the game by id
+	ClientCommandReplaySubTypeOpen = "open"
+	// ClientCommandReplaySubTypeControl pause, seek or change speed of the replay
+	ClientCommandReplaySubTypeControl = "control"
+	// ClientCommandReplaySubTypeClose leave the replay room
+	ClientCommandReplaySubTypeClose = "close"
 
 	// ClientCommandTypeGame namespace for commands about a game
 	ClientCommandTypeGame = "game"
@@ -22,6 +37,8 @@ const (
 	ClientCommandGameSubTypePickUp = "pickUp"
 	// ClientCommandGameSubTypeComplete complete round in game
 	ClientCommandGameSubTypeComplete = "complete"
+	// ClientCommandGameSubTypeTransfer transfer attack to the next player in game
+	ClientCommandGameSubTypeTransfer = "transfer"
 
 	// ClientCommandTypeRoom namespace for commands in room
 	ClientCommandTypeRoom = "room"
@@ -35,6 +52,12 @@ const (
 	ClientCommandRoomSubTypeStartGame = "startGame"
 	// ClientCommandRoomSubTypeDeleteGame command to delete the game in the room
 	ClientCommandRoomSubTypeDeleteGame = "deleteGame"
+	// ClientCommandRoomSubTypeSetGameRules command to set rules of the next game in the room
+	ClientCommandRoomSubTypeSetGameRules = "setGameRules"
+	// ClientCommandRoomSubTypeSetMemberTeam command to put a member of the room into a team by room owner
+	ClientCommandRoomSubTypeSetMemberTeam = "setMemberTeam"
+	// ClientCommandRoomSubTypeSetMatch command to set target of match series in the room by room owner
+	ClientCommandRoomSubTypeSetMatch = "setMatch"
 	// ClientCommandRoomSubTypeAddBot command to add a bot to the game
 	ClientCommandRoomSubTypeAddBot = "addBot"
 	// ClientCommandRoomSubTypeRemoveBots command to remove all bots from the game
@@ -1,22 +1,42 @@
 package main
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
+	"log"
 	"math/rand"
 	"strings"
 	"time"
 )
 
+// Supported sizes of the deck
+const (
+	DeckSize24 = 24
+	DeckSize36 = 36
+	DeckSize52 = 52
+)
+
 // Deck represents the deck of cars - talon
 type Deck struct {
 	cards []*Card
 }
 
-func newDeck() *Deck {
-	rand.Seed(time.Now().Unix())
-	cards := make([]*Card, 36)
+func isSupportedDeckSize(deckSize int) bool {
+	return deckSize == DeckSize24 || deckSize == DeckSize36 || deckSize == DeckSize52
+}
+
+// Returns values of cards in deck of given size: 24 - from "9", 36 - from "6", 52 - from "2"
+func getDeckValues(deckSize int) []string {
+	valuesNum := deckSize / len(cardSuits)
+	return cardValues[len(cardValues)-valuesNum:]
+}
+
+func newDeck(deckSize int) *Deck {
+	cards := make([]*Card, deckSize)
 	i := 0
-	for _, v := range cardValues {
+	for _, v := range getDeckValues(deckSize) {
 		for _, s := range cardSuits {
 			cards[i] = &Card{Value: v, Suit: s}
 			i = i + 1
@@ -27,9 +47,49 @@ func newDeck() *Deck {
 	}
 }
 
-func (p *Deck) shuffle() {
+// Source of random numbers to shuffle the deck. Numbers are hashes of the seed and the counter,
+// so the seed cannot be found by the order of cards. The source of math/rand keeps only 31 bits of the seed.
+type deckRandSource struct {
+	seed    int64
+	counter uint64
+}
+
+func newDeckRand(seed int64) *rand.Rand {
+	return rand.New(&deckRandSource{seed: seed})
+}
+
+// Returns the seed from the secure source, so it cannot be guessed by the time when the game starts
+func newDeckSeed() int64 {
+	seedBytes := make([]byte, 8)
+	if _, err := cryptorand.Read(seedBytes); err != nil {
+		log.Printf("Cannot read random seed: %s", err)
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(seedBytes))
+}
+
+func (s *deckRandSource) Seed(seed int64) {
+	s.seed = seed
+	s.counter = 0
+}
+
+func (s *deckRandSource) Uint64() uint64 {
+	block := make([]byte, 16)
+	binary.LittleEndian.PutUint64(block[:8], uint64(s.seed))
+	binary.LittleEndian.PutUint64(block[8:], s.counter)
+	s.counter++
+	hash := sha256.Sum256(block)
+	return binary.LittleEndian.Uint64(hash[:8])
+}
+
+func (s *deckRandSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Shuffles cards with the given source, so the same seed gives the same order
+func (p *Deck) shuffle(rng *rand.Rand) {
 	for i := range p.cards {
-		j := rand.Intn(i + 1)
+		j := rng.Intn(i + 1)
 		p.cards[i], p.cards[j] = p.cards[j], p.cards[i]
 	}
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DeckSaltSize is number of random bytes in salt of the deck commitment
+const DeckSaltSize = 16
+
+// DeckCommitment binds the server to the order of the shuffled deck.
+// Hash is published when the game starts, order and salt are revealed when the game ends.
+type DeckCommitment struct {
+	deckOrder string
+	salt      string
+	hash      string
+}
+
+func newDeckCommitment(deck *Deck) (*DeckCommitment, error) {
+	saltBytes := make([]byte, DeckSaltSize)
+	if _, err := rand.Read(saltBytes); err != nil {
+		return nil, err
+	}
+	deckOrder := cardsToString(deck.cards)
+	salt := hex.EncodeToString(saltBytes)
+
+	return &DeckCommitment{
+		deckOrder: deckOrder,
+		salt:      salt,
+		hash:      getDeckCommitmentHash(deckOrder, salt),
+	}, nil
+}
+
+// Returns hex of sha256 of salt and deck order separated by colon.
+// Deck order is cards from the bottom to the top, e.g. "6♣7♦10♥".
+func getDeckCommitmentHash(deckOrder string, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + deckOrder))
+	return hex.EncodeToString(sum[:])
+}
+
+func verifyDeckCommitment(hash string, deckOrder string, salt string) bool {
+	return getDeckCommitmentHash(deckOrder, salt) == hash
+}
+
+var gameLogDeckHashRegexp = regexp.MustCompile(`^ENTRY Game begins\..*deckHash=([0-9a-f]*);`)
+var gameLogDeckRevealRegexp = regexp.MustCompile(`^ENTRY Game ends\..*deckOrder=([^;]*);deckSalt=([0-9a-f]*);`)
+var gameLogDeckStateRegexp = regexp.MustCompile(`^deck=\d+:([^;]*);`)
+
+// Checks the game log written by GameFileLogger: revealed deck order should match the commitment
+// and the deck in every state of the game should be the bottom part of the revealed order.
+func verifyGameLog(r io.Reader) error {
+	var hash, deckOrder, salt string
+	hasReveal := false
+	deckStates := make([]string, 0)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := gameLogDeckHashRegexp.FindStringSubmatch(line); matches != nil {
+			hash = matches[1]
+		} else if matches := gameLogDeckRevealRegexp.FindStringSubmatch(line); matches != nil {
+			deckOrder, salt = matches[1], matches[2]
+			hasReveal = true
+		} else if matches := gameLogDeckStateRegexp.FindStringSubmatch(line); matches != nil {
+			deckStates = append(deckStates, matches[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return verifyGameDeck(hash, hasReveal, deckOrder, salt, deckStates)
+}
+
+// Checks the game log written by GameJsonLogger in the same way as the text log
+func verifyGameJsonLog(r io.Reader) error {
+	var hash, deckOrder, salt string
+	hasReveal := false
+	deckStates := make([]string, 0)
+
+	decoder := json.NewDecoder(r)
+	for {
+		var record GameJsonLogRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if record.DeckHash != "" {
+			hash = record.DeckHash
+		}
+		if record.Entry == "Game ends" {
+			var endEvent GameEndEvent
+			if err := json.Unmarshal(record.Action, &endEvent); err != nil {
+				return err
+			}
+			deckOrder, salt = endEvent.DeckOrder, endEvent.DeckSalt
+			hasReveal = true
+		}
+		if record.State != nil {
+			deckStates = append(deckStates, cardsToString(record.State.Deck))
+		}
+	}
+
+	return verifyGameDeck(hash, hasReveal, deckOrder, salt, deckStates)
+}
+
+func verifyGameDeck(hash string, hasReveal bool, deckOrder string, salt string, deckStates []string) error {
+	if hash == "" {
+		return errors.New("deck hash is not found in log")
+	}
+	if !hasReveal {
+		return errors.New("revealed deck order is not found in log")
+	}
+	if !verifyDeckCommitment(hash, deckOrder, salt) {
+		return fmt.Errorf("revealed deck order does not match hash %s", hash)
+	}
+	for i, deckState := range deckStates {
+		if !strings.HasPrefix(deckOrder, deckState) {
+			return fmt.Errorf("deck in state %d is not a part of revealed deck order: %s", i+1, deckState)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeckCommitment(t *testing.T) {
+	deck := newDeck(DeckSize36)
+	commitment, err := newDeckCommitment(deck)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(DeckSaltSize*2, len(commitment.salt))
+	assert.True(verifyDeckCommitment(commitment.hash, commitment.deckOrder, commitment.salt))
+
+	deck.cards[0], deck.cards[1] = deck.cards[1], deck.cards[0]
+	assert.False(verifyDeckCommitment(commitment.hash, cardsToString(deck.cards), commitment.salt))
+}
+
+func getTestGameLog(hash string, deckState string, deckOrder string, salt string) string {
+	return "ENTRY Game begins. ID=1; deckHash=" + hash + ";\n" +
+		"State:\n" +
+		"players=2 P=0(human):7♥; P=1(bot):8♥;\n" +
+		"deck=" + deckState + "battleground=0:;attacker=0;defender=1;trump=6♣;\n" +
+		"ENTRY Game ends. reason=loser;hasLoser=true;loserIndex=1;loserTeam=0;placings=0:1,1:2;" +
+		"deckOrder=" + deckOrder + ";deckSalt=" + salt + ";seed=1;\n"
+}
+
+func TestVerifyGameLog(t *testing.T) {
+	deckOrder := "6♣9♠8♥7♥"
+	salt := "0a1b"
+	hash := getDeckCommitmentHash(deckOrder, salt)
+
+	assert := assert.New(t)
+	assert.Nil(verifyGameLog(strings.NewReader(getTestGameLog(hash, "2:6♣9♠;", deckOrder, salt))))
+	assert.NotNil(verifyGameLog(strings.NewReader(getTestGameLog(hash, "2:6♣9♠;", "6♣8♥9♠7♥", salt))))
+	assert.NotNil(verifyGameLog(strings.NewReader(getTestGameLog(hash, "2:6♣8♥;", deckOrder, salt))))
+}
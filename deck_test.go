@@ -1,9 +1,12 @@
 package main
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+)
 
 func TestNewDeck(t *testing.T) {
-	deck := newDeck()
+	deck := newDeck(DeckSize36)
 	got := len(deck.cards)
 	expected := 36
 	if got != expected {
@@ -12,11 +15,11 @@ func TestNewDeck(t *testing.T) {
 }
 
 func TestShuffle(t *testing.T) {
-	deck := newDeck()
+	deck := newDeck(DeckSize36)
 	card1 := deck.cards[0]
 	card2 := deck.cards[1]
 	card3 := deck.cards[2]
-	deck.shuffle()
+	deck.shuffle(rand.New(rand.NewSource(1)))
 	scard1 := deck.cards[0]
 	scard2 := deck.cards[1]
 	scard3 := deck.cards[2]
@@ -26,8 +29,27 @@ func TestShuffle(t *testing.T) {
 	}
 }
 
+func TestShuffleWithSameSeed(t *testing.T) {
+	deck1 := newDeck(DeckSize36)
+	deck1.shuffle(rand.New(rand.NewSource(42)))
+	deck2 := newDeck(DeckSize36)
+	deck2.shuffle(rand.New(rand.NewSource(42)))
+	if deck1.asString() != deck2.asString() {
+		t.Errorf("TestShuffleWithSameSeed expected: %v, got: %v", deck1.asString(), deck2.asString())
+	}
+}
+
+func TestDeckRandDoesNotReduceSeed(t *testing.T) {
+	// Source of math/rand gives the same numbers for seeds which differ by 2^31-1
+	seed := int64(42)
+	otherSeed := seed + (1<<31 - 1)
+	if newDeckRand(seed).Int63() == newDeckRand(otherSeed).Int63() {
+		t.Errorf("TestDeckRandDoesNotReduceSeed expected different numbers for seeds %d and %d", seed, otherSeed)
+	}
+}
+
 func TestGetCard(t *testing.T) {
-	deck := newDeck()
+	deck := newDeck(DeckSize36)
 	card, err := deck.getCard()
 	if err != nil {
 		t.Fatalf("TestGetCard got error: %s", err)
@@ -41,7 +63,7 @@ func TestGetCard(t *testing.T) {
 }
 
 func TestGetCardOnEmptyDeck(t *testing.T) {
-	deck := newDeck()
+	deck := newDeck(DeckSize36)
 	deck.cards = deck.cards[:0]
 	_, err := deck.getCard()
 	if err == nil {
@@ -50,7 +72,7 @@ func TestGetCardOnEmptyDeck(t *testing.T) {
 }
 
 func TestAsString(t *testing.T) {
-	deck := newDeck()
+	deck := newDeck(DeckSize36)
 	deck.cards = deck.cards[:4]
 	got := deck.asString()
 	expected := "6♣ 6♦ 6♥ 6♠"
@@ -58,3 +80,27 @@ func TestAsString(t *testing.T) {
 		t.Errorf("TestAsString expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestNewShortDeck(t *testing.T) {
+	deck := newDeck(DeckSize24)
+	got := len(deck.cards)
+	expected := 24
+	if got != expected {
+		t.Errorf("TestNewShortDeck expected: %v, got: %v", expected, got)
+	}
+	deck.cards = deck.cards[:4]
+	gotString := deck.asString()
+	expectedString := "9♣ 9♦ 9♥ 9♠"
+	if gotString != expectedString {
+		t.Errorf("TestNewShortDeck expected: %v, got: %v", expectedString, gotString)
+	}
+}
+
+func TestNewFullDeck(t *testing.T) {
+	deck := newDeck(DeckSize52)
+	got := len(deck.cards)
+	expected := 52
+	if got != expected {
+		t.Errorf("TestNewFullDeck expected: %v, got: %v", expected, got)
+	}
+}
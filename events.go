@@ -14,6 +14,23 @@ const (
 	errorCantChangeStatusGameHasBeenStarted = "cant_change_status_game_has_been_started"
 	errorYouShouldBeOwner                   = "you_should_be_owner"
 	errorGameAlreadyDeleted                 = "game_already_deleted"
+	errorInvalidGameRules                   = "invalid_game_rules"
+	errorInvalidMatchSettings               = "invalid_match_settings"
+	errorInvalidTeam                        = "invalid_team"
+	errorTeamsAreNotEqual                   = "teams_are_not_equal"
+	errorGameSeedIsNotAllowed               = "game_seed_is_not_allowed"
+	errorNicknameIsTaken                    = "nickname_is_taken"
+	errorNicknameIsRegistered               = "nickname_is_registered"
+	errorInvalidNickname                    = "invalid_nickname"
+	errorPasswordIsTooShort                 = "password_is_too_short"
+	errorPasswordIsTooLong                  = "password_is_too_long"
+	errorTooManyLoginAttempts               = "too_many_login_attempts"
+	errorInvalidCredentials                 = "invalid_credentials"
+	errorAccountsAreUnavailable             = "accounts_are_unavailable"
+	errorSessionCannotBeResumed             = "session_cannot_be_resumed"
+	errorGameIsNotFound                     = "game_is_not_found"
+	errorReplayIsNotAllowedInRoom           = "replay_is_not_allowed_in_room"
+	errorInvalidBotLevel                    = "invalid_bot_level"
 )
 
 // JSONEvent represents a message to clients with some event.
@@ -12,6 +12,7 @@ type GameStateInfo struct {
 	CanYouPickUp     bool          `json:"canYouPickUp"`
 	CanYouAttack     bool          `json:"canYouAttack"`
 	CanYouComplete   bool          `json:"canYouComplete"`
+	CanYouTransfer   bool          `json:"canYouTransfer"`
 	HandsSizes       []int         `json:"handsSizes"`
 	DeckSize         int           `json:"deckSize"`
 	DiscardPileSize  int           `json:"discardPileSize"`
@@ -22,6 +23,11 @@ type GameStateInfo struct {
 	DefenderPickUp   bool          `json:"defenderPickUp"`
 	AttackerIndex    int           `json:"attackerIndex"`
 	DefenderIndex    int           `json:"defenderIndex"`
+	TurnPlayerIndex  int           `json:"turnPlayerIndex"`
+	MoveSecondsLeft  int           `json:"moveSecondsLeft"`
+	TimeBanksSeconds []int         `json:"timeBanksSeconds"`
+	// Cards of all players are shown in replays only
+	Hands [][]*Card `json:"hands,omitempty"`
 }
 
 // GameDealEvent contains info about game after the deal. It includes list of cards for each player.
@@ -39,6 +45,15 @@ type GameFirstAttackerEvent struct {
 // GameStartedEvent contains state when game was started
 type GameStartedEvent struct {
 	GameStateInfo *GameStateInfo `json:"gameStateInfo"`
+	GameRules     *GameRules     `json:"gameRules"`
+	// Hash of the deck order and salt which are revealed at the end of the game
+	DeckHash string `json:"deckHash"`
+}
+
+// GamePlayerConnectionEvent contains index of player whose connection dropped or was resumed
+type GamePlayerConnectionEvent struct {
+	PlayerIndex int  `json:"playerIndex"`
+	IsConnected bool `json:"isConnected"`
 }
 
 // GamePlayerLeftEvent contains index of player who left the game
@@ -63,6 +78,14 @@ type GameDefendEvent struct {
 	DefendingCard *Card          `json:"defendingCard"`
 }
 
+// GameTransferEvent contains info about transfer of attack to the next player
+type GameTransferEvent struct {
+	GameStateInfo    *GameStateInfo `json:"gameStateInfo"`
+	TransferrerIndex int            `json:"transferrerIndex"`
+	DefenderIndex    int            `json:"defenderIndex"`
+	Card             *Card          `json:"card"`
+}
+
 // GameStateEvent contains info about state only
 type GameStateEvent struct {
 	GameStateInfo *GameStateInfo `json:"gameStateInfo"`
@@ -76,6 +99,25 @@ type NewRoundEvent struct {
 
 // GameEndEvent contains info about winner
 type GameEndEvent struct {
-	HasLoser   bool `json:"hasLoser"`
-	LoserIndex int  `json:"loserIndex"`
+	Reason     string         `json:"reason"`
+	HasLoser   bool           `json:"hasLoser"`
+	LoserIndex int            `json:"loserIndex"`
+	LoserTeam  int            `json:"loserTeam"`
+	Placings   []*GamePlacing `json:"placings"`
+	// Order of the shuffled deck and salt to check hash from the beginning of the game
+	DeckOrder string `json:"deckOrder"`
+	DeckSalt  string `json:"deckSalt"`
+	// Seed which shuffled the deck is not known to players until the game ends
+	Seed int64 `json:"seed"`
+}
+
+// GameDrawEvent contains indexes of players who got rid of cards in the last round together, so nobody lost
+type GameDrawEvent struct {
+	PlayersIndexes []int `json:"playersIndexes"`
+}
+
+// GamePlacing contains place of a player in ended game. Players who got rid of cards in the same round share a place.
+type GamePlacing struct {
+	PlayerIndex int `json:"playerIndex"`
+	Place       int `json:"place"`
 }
@@ -11,16 +11,36 @@ type RoomInList struct {
 
 // ClientInList contains short info about client in the lobby.
 type ClientInList struct {
-	Id       uint64 `json:"id"`
-	Nickname string `json:"nickname"`
+	Id        uint64 `json:"id"`
+	Nickname  string `json:"nickname"`
+	AccountId uint64 `json:"accountId"`
+	Rating    int    `json:"rating"`
 }
 
 // ClientJoinedEvent contains info for the just connected client.
 type ClientJoinedEvent struct {
-	YourId       uint64          `json:"yourId"`
-	YourNickname string          `json:"yourNickname"`
-	Clients      []*ClientInList `json:"clients"`
-	Rooms        []*RoomInList   `json:"rooms"`
+	YourId        uint64          `json:"yourId"`
+	YourNickname  string          `json:"yourNickname"`
+	YourAccountId uint64          `json:"yourAccountId"`
+	YourRating    int             `json:"yourRating"`
+	Clients       []*ClientInList `json:"clients"`
+	Rooms         []*RoomInList   `json:"rooms"`
+	// Token to resume the session with a new connection if this one drops
+	SessionToken string `json:"sessionToken"`
+}
+
+// ClientLoggedInEvent contains account of the client and login token to log in without password next time.
+type ClientLoggedInEvent struct {
+	AccountId uint64 `json:"accountId"`
+	Nickname  string `json:"nickname"`
+	Token     string `json:"token"`
+}
+
+// LobbyLoginCommandData represents credentials to register or log in: nickname with password or login token.
+type LobbyLoginCommandData struct {
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
 }
 
 // ClientLeftEvent contains id of client who left lobby.
@@ -30,8 +50,22 @@ type ClientLeftEvent struct {
 
 // ClientBroadCastJoinedEvent contains info for other clients when a new client was connected.
 type ClientBroadCastJoinedEvent struct {
-	Id       uint64 `json:"id"`
-	Nickname string `json:"nickname"`
+	Id        uint64 `json:"id"`
+	Nickname  string `json:"nickname"`
+	AccountId uint64 `json:"accountId"`
+	Rating    int    `json:"rating"`
+}
+
+// ClientRatingUpdatedEvent contains new rating of a client after a rated game
+type ClientRatingUpdatedEvent struct {
+	Id     uint64 `json:"id"`
+	Rating int    `json:"rating"`
+	Change int    `json:"change"`
+}
+
+// LeaderboardEvent contains leaderboards of all periods and orders
+type LeaderboardEvent struct {
+	Leaderboards []*Leaderboard `json:"leaderboards"`
 }
 
 // ClientCreatedRoomEvent contains info of created room.
@@ -48,3 +82,28 @@ type RoomInListRemovedEvent struct {
 type RoomInListUpdatedEvent struct {
 	Room *RoomInList `json:"room"`
 }
+
+// ReplayOpenedEvent contains info of the game which is played back in the replay room
+type ReplayOpenedEvent struct {
+	GameId   string `json:"gameId"`
+	StepsNum int    `json:"stepsNum"`
+}
+
+// ReplayStateEvent contains position and controls of the replay
+type ReplayStateEvent struct {
+	Step      int     `json:"step"`
+	StepsNum  int     `json:"stepsNum"`
+	IsPlaying bool    `json:"isPlaying"`
+	Speed     float64 `json:"speed"`
+}
+
+// ReplayClosedEvent is sent when the client leaves the replay room
+type ReplayClosedEvent struct {
+}
+
+// ReplayControlCommandData contains changes of playing back, empty fields are not changed
+type ReplayControlCommandData struct {
+	IsPlaying *bool    `json:"isPlaying"`
+	Step      *int     `json:"step"`
+	Speed     *float64 `json:"speed"`
+}
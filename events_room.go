@@ -7,6 +7,9 @@ type RoomMemberInfo struct {
 	WantToPlay bool   `json:"wantToPlay"`
 	IsPlayer   bool   `json:"isPlayer"`
 	IsBot      bool   `json:"isBot"`
+	Team       int    `json:"team"`
+	Rating     int    `json:"rating"`
+	BotLevel   string `json:"botLevel,omitempty"`
 }
 
 // RoomInfo contains info about room where client is.
@@ -17,6 +20,8 @@ type RoomInfo struct {
 	GameStatus string            `json:"gameStatus"`
 	Members    []*RoomMemberInfo `json:"members"`
 	MaxPlayers int               `json:"maxPlayers"`
+	GameRules  *GameRules        `json:"gameRules"`
+	Match      *MatchSettings    `json:"match"`
 }
 
 // RoomJoinedEvent contains info about room where client is
@@ -39,6 +44,31 @@ type RoomMemberChangedPlayerStatusEvent struct {
 	Room *RoomMemberInfo `json:"member"`
 }
 
+// RoomScoreboardEvent contains cumulative results of members in the match
+type RoomScoreboardEvent struct {
+	Match       *MatchSettings `json:"match"`
+	GamesPlayed int            `json:"gamesPlayed"`
+	Scores      []*MatchScore  `json:"scores"`
+	IsOver      bool           `json:"isOver"`
+}
+
+// RoomStartGameCommandData represents optional data from room owner to start the game.
+// Seed reproduces shuffling of a previous game and is allowed only in non-production environment.
+type RoomStartGameCommandData struct {
+	Seed *int64 `json:"seed"`
+}
+
+// RoomAddBotCommandData represents optional data from room owner to add a bot of the difficulty level
+type RoomAddBotCommandData struct {
+	Level string `json:"level"`
+}
+
+// RoomSetMemberTeamCommandData represents data from room owner to put a member into a team
+type RoomSetMemberTeamCommandData struct {
+	MemberId uint64 `json:"memberId"`
+	Team     int    `json:"team"`
+}
+
 // RoomSetPlayerStatusCommandData represents data from room owner to set or unset player status of a member
 type RoomSetPlayerStatusCommandData struct {
 	MemberId uint64 `json:"memberId"`
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -12,30 +14,44 @@ const (
 	GameStatusPlaying   = "playing"
 	GameStatusEnd       = "end"
 
-	AfkTimeoutSeconds = 120
+	DefaultMoveSeconds = 120
+	MinMoveSeconds     = 5
 )
 
-// Game represents status, state, etc of the game.
+// Reasons of the end of the game.
+const (
+	GameEndReasonLoser      = "loser"
+	GameEndReasonDraw       = "draw"
+	GameEndReasonPlayerLeft = "player_left"
+	GameEndReasonPlayerAfk  = "player_afk"
+	GameEndReasonDeleted    = "deleted"
+)
+
+// Game plays the state of the game with connected clients: it receives their actions, keeps time of moves,
+// logs the game and sends events to players.
 type Game struct {
-	id                            string
-	playerActions                 chan *PlayerAction
-	owner                         *Player
-	room                          *Room
-	status                        string
-	players                       []*Player
-	deck                          *Deck
-	discardPileSize               int
-	trumpSuit                     string
-	trumpCard                     *Card
-	trumpCardIsOwnedByPlayerIndex int
-	firstAttackerReasonCard       *Card
-	attackerIndex                 int
-	defenderIndex                 int
-	battleground                  []*Card
-	defendingCards                map[int]*Card
-	defenderPickUp                bool
-	afkTimers                     map[int]*time.Timer
-	gameLogger                    GameLogger
+	id                 string
+	playerActions      chan *PlayerAction
+	moveTimeouts       chan *MoveTimeout
+	suspendRequests    chan chan *GameSnapshot
+	deleteRequests     chan struct{}
+	removedClients     chan ClientSender
+	connections        chan *PlayerConnection
+	loopDone           chan struct{}
+	owner              *Player
+	room               *Room
+	status             string
+	statusMutex        sync.Mutex
+	players            []*Player
+	state              *GameState
+	leadingPlayerIndex int
+	rules              *GameRules
+	seed               int64
+	rng                *rand.Rand
+	deckCommitment     *DeckCommitment
+	clock              *GameClock
+	gameLogger         GameLogger
+	startedAt          time.Time
 }
 
 // GameLogger stores game events
@@ -43,18 +59,36 @@ type GameLogger interface {
 	// Save event when game starts
 	LogGameBegins(game *Game)
 	// Save event when a player attacks with card
-	LogPlayerActionAttack(game *Game, data AttackActionData)
+	LogPlayerActionAttack(game *Game, player *Player, data AttackActionData)
 	// Save event when a player defends card
-	LogPlayerActionDefend(game *Game, data DefendActionData)
+	LogPlayerActionDefend(game *Game, player *Player, data DefendActionData)
+	// Save event when a defender transfers attack to the next player
+	LogPlayerActionTransfer(game *Game, player *Player, data TransferActionData)
 	// Save event when a player picks up cards from desk
-	LogPlayerActionPickUp(game *Game)
+	LogPlayerActionPickUp(game *Game, player *Player)
 	// Save event when a players completes a round
-	LogPlayerActionComplete(game *Game)
+	LogPlayerActionComplete(game *Game, player *Player)
+	// Save event when the last players get rid of cards in the same round
+	LogGameDraw(game *Game, data *GameDrawEvent)
+	// Save event when an action of a player is not allowed by rules
+	LogPlayerActionRejected(game *Game, player *Player, actionName string, data interface{})
+	// Save event when a round ends and cards are picked up or discarded
+	LogRoundEnds(game *Game, wasAttackSuccessful bool)
+	// Save event when an active player leaves the game or is removed for being away
+	LogPlayerLeft(game *Game, playerIndex int, isAfk bool)
+	// Save event when a spectator joins the running game
+	LogLatePlayerJoin(game *Game, player *Player)
 	// Save event when game ends
-	LogGameEnds(game *Game, hasLoser bool, loserIndex int)
+	LogGameEnds(game *Game, data *GameEndEvent)
+	// Save event when the owner of the room deletes the game, the game is ended with reason "deleted" before
+	LogGameDeleted(game *Game)
+	// Save event when the running game is saved to snapshot on server shutdown
+	LogGameSuspended(game *Game)
+	// Save event when the game is restored from snapshot on server startup
+	LogGameRestored(game *Game)
 }
 
-func newGame(room *Room, players []*Player, gameLogger GameLogger) *Game {
+func newGame(room *Room, players []*Player, gameLogger GameLogger, rules *GameRules) *Game {
 	currentTime := time.Now()
 	gameId := fmt.Sprintf(
 		"%d%02d%02d_%02d%02d%02d_%d",
@@ -66,113 +100,91 @@ func newGame(room *Room, players []*Player, gameLogger GameLogger) *Game {
 		currentTime.Second(),
 		room.id,
 	)
+	seed := newDeckSeed()
 	return &Game{
-		id:             gameId,
-		room:           room,
-		playerActions:  make(chan *PlayerAction),
-		status:         GameStatusPreparing,
-		players:        players,
-		battleground:   make([]*Card, 0),
-		defendingCards: make(map[int]*Card, 0),
-		afkTimers:      make(map[int]*time.Timer, 0),
-		gameLogger:     gameLogger,
-	}
+		id:                 gameId,
+		room:               room,
+		playerActions:      make(chan *PlayerAction),
+		moveTimeouts:       make(chan *MoveTimeout, 1),
+		suspendRequests:    make(chan chan *GameSnapshot),
+		deleteRequests:     make(chan struct{}),
+		removedClients:     make(chan ClientSender),
+		connections:        make(chan *PlayerConnection),
+		loopDone:           make(chan struct{}),
+		status:             GameStatusPreparing,
+		players:            players,
+		state:              newGameState(rules, players),
+		leadingPlayerIndex: -1,
+		clock:              newGameClock(rules, len(players)),
+		gameLogger:         gameLogger,
+		rules:              rules,
+		seed:               seed,
+		rng:                newDeckRand(seed),
+		deckCommitment:     &DeckCommitment{},
+		startedAt:          currentTime,
+	}
+}
+
+// Changes status of the game, the status is read by the lobby while the game is running
+func (g *Game) setStatus(status string) {
+	g.statusMutex.Lock()
+	defer g.statusMutex.Unlock()
+	g.status = status
+}
+
+// Returns status of the game to the lobby, the game itself reads the field
+func (g *Game) getStatus() string {
+	g.statusMutex.Lock()
+	defer g.statusMutex.Unlock()
+	return g.status
+}
+
+// Replaces random seed of the game to reproduce shuffling of a previous game
+func (g *Game) setSeed(seed int64) {
+	g.seed = seed
+	g.rng = newDeckRand(seed)
 }
 
 func (g *Game) sendPlayersEvent() {
-	pe := GamePlayersEvent{
-		YourPlayerIndex: -1,
-		Players:         g.players,
-	}
 	for i, p := range g.players {
-		pe.YourPlayerIndex = i
-		p.sendEvent(pe)
-	}
-}
-
-func (g *Game) deal() {
-	cardsLimit := 6
-	var lastCard *Card
-	lastPlayerIndex := -1
-	for cardIndex := 0; cardIndex < cardsLimit; cardIndex = cardIndex + 1 {
-		for playerIndex, p := range g.players {
-			if !p.IsActive || len(p.cards) >= cardsLimit {
-				break
-			}
-			card, err := g.deck.getCard()
-			if err == nil {
-				p.cards = append(p.cards, card)
-				lastCard = card
-				lastPlayerIndex = playerIndex
-			}
-		}
-	}
-	if len(g.deck.cards) > 0 {
-		lastCard = g.deck.cards[0]
-		g.trumpCardIsOwnedByPlayerIndex = -1
-	} else {
-		g.trumpCardIsOwnedByPlayerIndex = lastPlayerIndex
-	}
-
-	g.trumpCard = lastCard
-	if lastCard != nil {
-		g.trumpSuit = lastCard.Suit
+		p.sendEvent(g.newPlayersEvent(i))
 	}
 }
 
-func (g *Game) dealToPlayer(player *Player) {
-	if !player.IsActive {
-		return
-	}
-	cardsLimit := 6
-	for cardIndex := len(player.cards); cardIndex < cardsLimit; cardIndex = cardIndex + 1 {
-		if len(player.cards) >= cardsLimit {
-			break
-		}
-		card, err := g.deck.getCard()
-		if err == nil {
-			player.cards = append(player.cards, card)
-		}
+func (g *Game) newPlayersEvent(yourPlayerIndex int) GamePlayersEvent {
+	for i, p := range g.players {
+		p.IsActive = g.getPlayerState(i).isActive
 	}
-	if len(player.cards) == 0 {
-		player.IsActive = false
+	return GamePlayersEvent{
+		YourPlayerIndex: yourPlayerIndex,
+		Players:         g.players,
 	}
 }
 
-func (g *Game) roundDeal(firstIndex int, lastIndex int) {
-	g.dealToPlayer(g.players[firstIndex])
-	for index, p := range g.players {
-		if index != firstIndex && index != lastIndex {
-			g.dealToPlayer(p)
-		}
+// Returns state of the player with given index. Spectators who joined the running game have no cards in the state.
+func (g *Game) getPlayerState(playerIndex int) *PlayerState {
+	if !g.state.isPlayerIndexValid(playerIndex) {
+		return &PlayerState{cards: make([]*Card, 0), team: g.players[playerIndex].Team}
 	}
-	g.dealToPlayer(g.players[lastIndex])
+	return g.state.players[playerIndex]
 }
 
 func (g *Game) getGameStateInfo(player *Player) *GameStateInfo {
-	gsi := &GameStateInfo{
-		YourHand:         make([]*Card, 0),
-		HandsSizes:       make([]int, len(g.players)),
-		DeckSize:         len(g.deck.cards),
-		DiscardPileSize:  g.discardPileSize,
-		TrumpCard:        g.trumpCard,
-		Battleground:     g.battleground,
-		DefendingCards:   g.defendingCards,
-		CompletedPlayers: make(map[int]bool, 0),
-		DefenderPickUp:   g.defenderPickUp,
-		AttackerIndex:    g.attackerIndex,
-		DefenderIndex:    g.defenderIndex,
+	gsi := g.state.getGameStateInfo(g.getPlayerIndex(player))
+	if g.status != GameStatusPlaying {
+		gsi.CanYouComplete = false
+		gsi.CanYouAttack = false
+		gsi.CanYouPickUp = false
+		gsi.CanYouTransfer = false
 	}
-
-	for i, p := range g.players {
-		if p == player {
-			gsi.YourHand = p.cards
-			gsi.CanYouComplete = g.canPlayerComplete(player)
-			gsi.CanYouAttack = g.canPlayerAttack(player)
-			gsi.CanYouPickUp = g.canPlayerPickUp(player)
-		}
-		gsi.HandsSizes[i] = len(p.cards)
-		gsi.CompletedPlayers[i] = p.IsCompleted
+	for len(gsi.HandsSizes) < len(g.players) {
+		gsi.HandsSizes = append(gsi.HandsSizes, 0)
+	}
+	gsi.TurnPlayerIndex = g.clock.waitingPlayerIndex
+	gsi.MoveSecondsLeft = int(g.clock.getMoveTimeLeft().Seconds())
+	gsi.TimeBanksSeconds = make([]int, len(g.players))
+	for i := range g.players {
+		gsi.TimeBanksSeconds[i] = int(g.clock.getTimeBankLeft(i).Seconds())
 	}
 
 	return gsi
@@ -180,7 +192,7 @@ func (g *Game) getGameStateInfo(player *Player) *GameStateInfo {
 
 func (g *Game) sendDealEvent() {
 	de := GameDealEvent{
-		TrumpCardIsOwnedByPlayerIndex: g.trumpCardIsOwnedByPlayerIndex,
+		TrumpCardIsOwnedByPlayerIndex: g.state.trumpCardIsOwnedByPlayerIndex,
 	}
 
 	for _, p := range g.players {
@@ -191,76 +203,23 @@ func (g *Game) sendDealEvent() {
 
 func (g *Game) prepare() {
 	g.sendPlayersEvent()
-	g.deck = newDeck()
-	g.deck.shuffle()
-	g.deal()
+	deck := newDeck(g.rules.DeckSize)
+	deck.shuffle(g.rng)
+	deckCommitment, err := newDeckCommitment(deck)
+	if err != nil {
+		log.Printf("Cannot make deck commitment: %s", err)
+		deckCommitment = &DeckCommitment{}
+	}
+	g.deckCommitment = deckCommitment
+	g.state.deal(deck)
 	g.sendDealEvent()
-	g.attackerIndex, g.defenderIndex, g.firstAttackerReasonCard = g.findFirstAttacker()
+	g.state.chooseFirstAttacker(g.leadingPlayerIndex)
 	g.sendFirstAttackerEvent()
 }
 
-func (g *Game) findFirstAttacker() (firstAttackerIndex int, defenderIndex int, lowestTrumpCard *Card) {
-	firstAttackerIndex = -1
-	defenderIndex = -1
-	lowestTrumpCard = &Card{"A", g.trumpSuit}
-
-	for playerIndex, p := range g.players {
-		for _, c := range p.cards {
-			if c.Suit == g.trumpSuit && c.lte(lowestTrumpCard) {
-				firstAttackerIndex = g.adjustPlayerIndex(playerIndex)
-				defenderIndex = g.adjustPlayerIndex(firstAttackerIndex + 1)
-				lowestTrumpCard = c
-			}
-		}
-	}
-
-	if firstAttackerIndex >= 0 {
-		return
-	}
-
-	// fallback
-	for playerIndex, p := range g.players {
-		if !p.IsActive {
-			break
-		}
-		for _, c := range p.cards {
-			if c.lte(lowestTrumpCard) {
-				firstAttackerIndex = playerIndex
-				defenderIndex = g.adjustPlayerIndex(firstAttackerIndex + 1)
-				lowestTrumpCard = c
-			}
-		}
-	}
-
-	return
-}
-
-func (g *Game) findNewAttacker(wasPickUp bool) (attackerIndex int, defenderIndex int) {
-	attackerIndex = g.attackerIndex + 1
-	defenderIndex = g.defenderIndex + 1
-
-	if wasPickUp {
-		if g.getActivePlayersNum() == 2 {
-			attackerIndex = g.attackerIndex
-			defenderIndex = g.defenderIndex
-		} else {
-			attackerIndex = attackerIndex + 1
-			defenderIndex = defenderIndex + 1
-		}
-
-	}
-
-	attackerIndex, defenderIndex = g.adjustPlayerIndex(attackerIndex), g.adjustPlayerIndex(defenderIndex)
-	if attackerIndex == defenderIndex {
-		attackerIndex = g.adjustPlayerIndex(attackerIndex + 1)
-	}
-
-	return
-}
-
 func (g *Game) sendFirstAttackerEvent() {
 	fae := GameFirstAttackerEvent{
-		ReasonCard: g.firstAttackerReasonCard,
+		ReasonCard: g.state.firstAttackerReasonCard,
 	}
 	for _, p := range g.players {
 		fae.GameStateInfo = g.getGameStateInfo(p)
@@ -270,9 +229,10 @@ func (g *Game) sendFirstAttackerEvent() {
 
 func (g *Game) begin() {
 	g.prepare()
-	g.status = GameStatusPlaying
+	g.setStatus(GameStatusPlaying)
+	g.restartMoveTimer()
 
-	gse := &GameStartedEvent{}
+	gse := &GameStartedEvent{GameRules: g.rules, DeckHash: g.deckCommitment.hash}
 	for _, p := range g.players {
 		gse.GameStateInfo = g.getGameStateInfo(p)
 		p.sendEvent(gse)
@@ -280,292 +240,185 @@ func (g *Game) begin() {
 
 	g.gameLogger.LogGameBegins(g)
 	g.room.onGameStarted()
-	for {
+	g.loop()
+}
+
+// Handles actions of players until the game ends, the ended game is passed to the lobby
+func (g *Game) loop() {
+	for g.status == GameStatusPlaying {
 		select {
-		case action, ok := <-g.playerActions:
-			if !ok {
-				return
-			}
+		case action := <-g.playerActions:
 			g.onClientAction(action)
+		case timeout := <-g.moveTimeouts:
+			if g.clock.isCurrentTimeout(timeout) {
+				log.Println("move timeout", timeout.playerIndex)
+				g.clock.stop()
+				g.onMoveTimeout(timeout.playerIndex)
+			}
+		case client := <-g.removedClients:
+			g.onClientRemoved(client)
+		case connection := <-g.connections:
+			g.onPlayerConnectionChanged(connection)
+		case <-g.deleteRequests:
+			g.end(GameEndReasonDeleted)
+		case snapshotResult := <-g.suspendRequests:
+			// The suspended game does not accept moves anymore
+			snapshotResult <- g.suspend()
+			close(g.loopDone)
+			return
 		}
 	}
+	close(g.loopDone)
+	// Rooms and matches are changed by the lobby only
+	g.room.lobby.endedGames <- g
 }
 
-func (g *Game) canPlayerAttack(player *Player) bool {
-	if g.status != GameStatusPlaying {
-		return false
+// Passes the action of the player to the loop of the game, actions are dropped when the game is over
+func (g *Game) sendAction(action *PlayerAction) {
+	select {
+	case g.playerActions <- action:
+	case <-g.loopDone:
 	}
-	if g.defenderIndex == g.getPlayerIndex(player) {
-		return false
-	}
-	if len(g.battleground) == 0 && g.attackerIndex != g.getPlayerIndex(player) {
-		return false
-	}
-	if len(g.battleground) >= 6 {
-		return false
-	}
-	if len(g.battleground) >= len(g.players[g.defenderIndex].cards)+len(g.defendingCards) {
-		return false
-	}
-	if player.IsCompleted {
-		return false
-	}
-
-	return true
 }
 
-func (g *Game) canPlayerAttackWithCard(player *Player, card *Card) bool {
-	if !g.canPlayerAttack(player) {
-		return false
-	}
-	if !player.hasCard(card) {
-		return false
-	}
-	if len(g.battleground) > 0 && (!g.hasBattlegroundSameValue(card) && !g.hasDefendingCardsSameValue(card)) {
-		return false
+// Passes the client who left the room to the loop of the game, the active player leaves the running game
+func (g *Game) removeClient(client ClientSender) {
+	select {
+	case g.removedClients <- client:
+	case <-g.loopDone:
 	}
-
-	return true
 }
 
-func (g *Game) canPlayerDefendWithCard(player *Player, attackingCard *Card, defendingCard *Card) bool {
-	if g.status != GameStatusPlaying {
-		return false
-	}
-	if !player.hasCard(defendingCard) {
-		return false
-	}
-	if g.defenderIndex != g.getPlayerIndex(player) {
+// Tells the loop of the game that connection of the client dropped.
+// Returns false if the client is not an active player of the running game, so the seat is not held for him.
+func (g *Game) dropConnection(client ClientSender) bool {
+	connection := &PlayerConnection{client: client, isActivePlayer: make(chan bool, 1)}
+	select {
+	case g.connections <- connection:
+		return <-connection.isActivePlayer
+	case <-g.loopDone:
 		return false
 	}
-	if !g.hasBattlegroundCard(attackingCard) {
-		return false
-	}
-	if g.trumpSuit != defendingCard.Suit && g.trumpSuit == attackingCard.Suit {
-		return false
-	}
-	if g.trumpSuit == defendingCard.Suit && g.trumpSuit != attackingCard.Suit {
-		return true
-	}
-	return defendingCard.gt(attackingCard)
 }
 
-func (g *Game) attack(player *Player, data AttackActionData) {
-	card := data.Card
-	canAttack := g.canPlayerAttackWithCard(player, card)
-	if !canAttack {
-		return
-	}
-	g.battleground = append(g.battleground, card)
-	player.removeCard(card)
-	g.gameLogger.LogPlayerActionAttack(g, data)
-
-	gameAttackEvent := GameAttackEvent{
-		AttackerIndex: g.getPlayerIndex(player),
-		DefenderIndex: g.defenderIndex,
-		Card:          card,
-	}
-
-	for _, p := range g.players {
-		gameAttackEvent.GameStateInfo = g.getGameStateInfo(p)
-		p.sendEvent(gameAttackEvent)
+// Moves the seat of the client whose connection dropped to the client with a new connection
+func (g *Game) resumeConnection(client ClientSender, newClient ClientSender) {
+	connection := &PlayerConnection{client: client, newClient: newClient, isActivePlayer: make(chan bool, 1)}
+	select {
+	case g.connections <- connection:
+		<-connection.isActivePlayer
+	case <-g.loopDone:
+		// The loop does not change the ended game anymore
+		for _, p := range g.players {
+			if p.client == client {
+				p.client = newClient
+			}
+		}
 	}
-
-	g.restartAfkTimers(g.defenderIndex)
 }
 
-func (g *Game) defend(player *Player, data DefendActionData) {
-	canDefend := g.canPlayerDefendWithCard(player, data.AttackingCard, data.DefendingCard)
-	if !canDefend {
-		log.Printf("Cannot use card to defend %+v %+v", data.AttackingCard, data.DefendingCard)
-		return
-	}
-	attackingIndex := g.getBattlegroundCardIndex(data.AttackingCard)
-	g.defendingCards[attackingIndex] = data.DefendingCard
-	player.removeCard(data.DefendingCard)
-
-	g.gameLogger.LogPlayerActionDefend(g, data)
-
-	// Allow other players to attack in the middle of a round
-	g.resetPlayersCompleteStatuses()
-
-	gameAttackEvent := GameDefendEvent{
-		DefenderIndex: g.getPlayerIndex(player),
-		AttackingCard: data.AttackingCard,
-		DefendingCard: data.DefendingCard,
-	}
-
-	for _, p := range g.players {
-		gameAttackEvent.GameStateInfo = g.getGameStateInfo(p)
-		p.sendEvent(gameAttackEvent)
-	}
-
-	if len(g.defendingCards) == len(g.battleground) {
-		// All cards are beaten, wait for attacker
-		g.restartAfkTimers(g.attackerIndex)
-	} else {
-		// Not all cards are beaten, wait for defender
-		g.restartAfkTimers(g.defenderIndex)
+// Ends the running game in its loop and waits until the loop exits, so the game is not changed anymore
+func (g *Game) delete() {
+	select {
+	case g.deleteRequests <- struct{}{}:
+	case <-g.loopDone:
 	}
+	<-g.loopDone
 }
 
-func (g *Game) canPlayerPickUp(player *Player) bool {
-	if g.status != GameStatusPlaying {
-		return false
-	}
-	if !player.IsActive {
-		return false
-	}
-	if g.defenderIndex != g.getPlayerIndex(player) {
-		return false
-	}
-
-	if len(g.battleground) == 0 {
-		return false
-	}
-	if g.defenderPickUp {
-		return false
-	}
-
-	return true
+func (g *Game) onClientAction(action *PlayerAction) {
+	g.applyAction(GameAction{PlayerIndex: g.getPlayerIndex(action.player), Name: action.Name, Data: action.Data})
 }
 
-func (g *Game) pickUp(player *Player) {
-	if !g.canPlayerPickUp(player) {
+// Makes the move in the state of the game, logs it and sends events about it to players
+func (g *Game) applyAction(action GameAction) {
+	if g.status != GameStatusPlaying || action.PlayerIndex < 0 {
 		return
 	}
-
-	g.defenderPickUp = true
-	g.gameLogger.LogPlayerActionPickUp(g)
-
-	if g.areAllPlayersCompleted() {
-		g.endRound()
-	} else {
-		g.broadcastGameStateEvent()
+	player := g.players[action.PlayerIndex]
+	state, events, err := g.state.apply(action)
+	if err != nil {
+		log.Printf("Rejected action: %s", err)
+		g.gameLogger.LogPlayerActionRejected(g, player, action.Name, action.Data)
+		return
 	}
 
-	g.restartAfkTimers(g.defenderIndex)
-}
-
-func (g *Game) canPlayerComplete(player *Player) bool {
-	if g.status != GameStatusPlaying {
-		return false
-	}
-	if !player.IsActive {
-		return false
-	}
-	if g.defenderIndex == g.getPlayerIndex(player) &&
-		(len(g.battleground) != len(g.defendingCards) || !g.areAllAttackersCompleted()) {
-		return false
-	}
-	if g.attackerIndex == g.getPlayerIndex(player) && len(g.battleground) == 0 {
-		return false
-	}
-	if player.IsCompleted {
-		return false
+	// The move which ends the round is logged with cards of the round on the table
+	if state.roundsPlayed == g.state.roundsPlayed {
+		g.state = state
 	}
+	g.logPlayerAction(player, action)
+	g.state = state
 
-	if len(g.battleground) != len(g.defendingCards) && !g.defenderPickUp {
-		return false
+	if !g.state.isEnded {
+		g.restartMoveTimer()
 	}
-
-	return true
+	g.sendStateEvents(events)
 }
 
-func (g *Game) complete(player *Player) {
-	if !g.canPlayerComplete(player) {
-		return
-	}
-
-	player.IsCompleted = true
-	g.gameLogger.LogPlayerActionComplete(g)
-
-	if g.areAllPlayersCompleted() {
-		g.endRound()
-	} else {
-		g.broadcastGameStateEvent()
+func (g *Game) logPlayerAction(player *Player, action GameAction) {
+	switch action.Name {
+	case PlayerActionNameAttack:
+		g.gameLogger.LogPlayerActionAttack(g, player, action.Data.(AttackActionData))
+	case PlayerActionNameDefend:
+		g.gameLogger.LogPlayerActionDefend(g, player, action.Data.(DefendActionData))
+	case PlayerActionNameTransfer:
+		g.gameLogger.LogPlayerActionTransfer(g, player, action.Data.(TransferActionData))
+	case PlayerActionNamePickUp:
+		g.gameLogger.LogPlayerActionPickUp(g, player)
+	case PlayerActionNameComplete:
+		g.gameLogger.LogPlayerActionComplete(g, player)
 	}
 }
 
-func (g *Game) endRound() {
-	g.resetPlayersCompleteStatuses()
-	// Can change number of active players to end game
-	g.roundDeal(g.attackerIndex, g.defenderIndex)
-
-	defenderPlayer := g.players[g.defenderIndex]
-	wasAttackSuccessful := g.defenderPickUp
-
-	if g.defenderPickUp {
-		for _, c := range g.battleground {
-			defenderPlayer.cards = append(defenderPlayer.cards, c)
-		}
-		for _, c := range g.defendingCards {
-			defenderPlayer.cards = append(defenderPlayer.cards, c)
-		}
-	} else {
-		g.discardPileSize = g.discardPileSize + len(g.battleground) + len(g.defendingCards)
-	}
-
-	g.attackerIndex, g.defenderIndex = g.findNewAttacker(g.defenderPickUp)
-	g.battleground = make([]*Card, 0)
-	g.defendingCards = make(map[int]*Card, 0)
-	g.defenderPickUp = false
-
-	activePlayers := g.getActivePlayers()
-
-	if len(activePlayers) < 2 {
-		// End of game
-		hasLoser := false
-		loserIndex := -1
-		if len(activePlayers) == 1 {
-			hasLoser = true
-			loserIndex = g.getPlayerIndex(activePlayers[0])
-		}
-		g.endGame(hasLoser, loserIndex)
-		g.restartAfkTimers(-1)
-	} else {
-		nrd := NewRoundEvent{WasAttackSuccessful: wasAttackSuccessful}
-		for _, p := range g.players {
-			nrd.GameStateInfo = g.getGameStateInfo(p)
-			p.sendEvent(nrd)
+// Logs events of the state and sends them to players, events about moves get the state seen by each player
+func (g *Game) sendStateEvents(events []interface{}) {
+	for _, event := range events {
+		switch e := event.(type) {
+		case NewRoundEvent:
+			g.gameLogger.LogRoundEnds(g, e.WasAttackSuccessful)
+			if !g.state.isEnded {
+				g.broadcastStateEvent(e)
+			}
+		case *GameDrawEvent:
+			g.gameLogger.LogGameDraw(g, e)
+			g.room.broadcastEvent(e, nil)
+		case *GamePlayerLeftEvent:
+			g.gameLogger.LogPlayerLeft(g, e.PlayerIndex, e.IsAfk)
+			g.room.broadcastEvent(e, nil)
+		case *GameEndEvent:
+			g.endGame(e)
+		default:
+			g.broadcastStateEvent(e)
 		}
-		g.restartAfkTimers(g.attackerIndex)
 	}
 }
 
-func (g *Game) resetPlayersCompleteStatuses() {
+func (g *Game) broadcastStateEvent(event interface{}) {
 	for _, p := range g.players {
-		p.IsCompleted = false
+		gsi := g.getGameStateInfo(p)
+		switch e := event.(type) {
+		case GameAttackEvent:
+			e.GameStateInfo = gsi
+			p.sendEvent(e)
+		case GameDefendEvent:
+			e.GameStateInfo = gsi
+			p.sendEvent(e)
+		case GameTransferEvent:
+			e.GameStateInfo = gsi
+			p.sendEvent(e)
+		case NewRoundEvent:
+			e.GameStateInfo = gsi
+			p.sendEvent(e)
+		case GameStateEvent:
+			e.GameStateInfo = gsi
+			p.sendEvent(e)
+		}
 	}
 }
 
 func (g *Game) broadcastGameStateEvent() {
-	gameStateEvent := GameStateEvent{}
-
-	for _, p := range g.players {
-		gameStateEvent.GameStateInfo = g.getGameStateInfo(p)
-		p.sendEvent(gameStateEvent)
-	}
-}
-
-func (g *Game) onClientAction(action *PlayerAction) {
-	if action.Name == PlayerActionNameAttack {
-		data, ok := action.Data.(AttackActionData)
-		if ok {
-			g.attack(action.player, data)
-		}
-	} else if action.Name == PlayerActionNameDefend {
-		data, ok := action.Data.(DefendActionData)
-		if ok {
-			g.defend(action.player, data)
-		}
-	} else if action.Name == PlayerActionNamePickUp {
-		g.pickUp(action.player)
-	} else if action.Name == PlayerActionNameComplete {
-		g.complete(action.player)
-	} else {
-		log.Printf("Unknown game action: %s", action.Name)
-	}
+	g.broadcastStateEvent(GameStateEvent{})
 }
 
 func (g *Game) broadcastEvent(event interface{}) {
@@ -575,34 +428,39 @@ func (g *Game) broadcastEvent(event interface{}) {
 	}
 }
 
-func (g *Game) endGame(hasLoser bool, loserIndex int) {
+func (g *Game) endGame(gameEndEvent *GameEndEvent) {
 	if g.status != GameStatusPlaying {
 		return
 	}
-	g.status = GameStatusEnd
+	g.setStatus(GameStatusEnd)
+	g.clock.stop()
 	g.broadcastGameStateEvent()
-	gameEndEvent := &GameEndEvent{
-		HasLoser:   hasLoser,
-		LoserIndex: loserIndex,
-	}
-	g.gameLogger.LogGameEnds(g, hasLoser, loserIndex)
+	gameEndEvent.DeckOrder = g.deckCommitment.deckOrder
+	gameEndEvent.DeckSalt = g.deckCommitment.salt
+	gameEndEvent.Seed = g.seed
+	g.gameLogger.LogGameEnds(g, gameEndEvent)
 	g.room.broadcastEvent(gameEndEvent, nil)
-	close(g.playerActions)
-	g.room.onGameEnded()
+}
+
+// Ends the running game without loser, e.g. when the owner of the room deletes it
+func (g *Game) end(reason string) {
+	if g.status != GameStatusPlaying {
+		return
+	}
+	state, events := g.state.end(reason)
+	g.state = state
+	g.sendStateEvents(events)
 }
 
 func (g *Game) onActivePlayerLeft(playerIndex int, isAfk bool) {
 	log.Printf("active player left index: %d, is afk = %t", playerIndex, isAfk)
-	gamePlayerLeft := &GamePlayerLeftEvent{playerIndex, isAfk}
-	g.room.broadcastEvent(gamePlayerLeft, nil)
-
-	if g.getActivePlayersNum() == 2 {
-		log.Printf("ending game")
-		g.endGame(true, playerIndex)
-	}
+	state, events := g.state.leave(playerIndex, isAfk)
+	g.state = state
+	g.sendStateEvents(events)
 }
 
 func (g *Game) onLatePlayerJoin(player *Player) {
+	g.gameLogger.LogLatePlayerJoin(g, player)
 	g.sendPlayersEvent()
 
 	gameStateEvent := GameStateEvent{}
@@ -610,134 +468,120 @@ func (g *Game) onLatePlayerJoin(player *Player) {
 	player.sendEvent(gameStateEvent)
 }
 
-func (g *Game) onClientRemoved(client ClientSender) {
+func (g *Game) isActivePlayer(client ClientSender) bool {
+	if g.status != GameStatusPlaying {
+		return false
+	}
 	for index, p := range g.players {
-		if p.client.Id() == client.Id() && p.IsActive {
-			g.onActivePlayerLeft(index, false)
-			return
+		if p.client.Id() == client.Id() && g.getPlayerState(index).isActive {
+			return true
 		}
 	}
+	return false
 }
 
-func (g *Game) restartAfkTimers(waitingPlayerIndex int) {
-	for plIndex, timer := range g.afkTimers {
-		timer.Stop()
-		delete(g.afkTimers, plIndex)
-	}
-
-	if waitingPlayerIndex < 0 {
+// Tells other players that connection of the player dropped or was resumed.
+// The resumed player takes the seat and gets full state of the game.
+func (g *Game) onPlayerConnectionChanged(connection *PlayerConnection) {
+	isActivePlayer := g.isActivePlayer(connection.client)
+	defer func() {
+		connection.isActivePlayer <- isActivePlayer
+	}()
+	isConnected := connection.newClient != nil
+	if !isActivePlayer && !isConnected {
 		return
 	}
-
-	timer, ok := g.afkTimers[waitingPlayerIndex]
-	if ok {
-		log.Println("stopping timer", waitingPlayerIndex)
-		timer.Stop()
-	}
-
-	timer = time.AfterFunc(time.Second*AfkTimeoutSeconds, func() {
-		log.Println("time after func", waitingPlayerIndex)
-		delete(g.afkTimers, waitingPlayerIndex)
-		g.onActivePlayerLeft(waitingPlayerIndex, true)
-	})
-
-	g.afkTimers[waitingPlayerIndex] = timer
-}
-
-func (g *Game) findPlayerOfClient(client *Client) *Player {
-	for _, p := range g.players {
-		if p.client.Id() == client.Id() {
-			return p
+	for index, p := range g.players {
+		if p.client != connection.client {
+			continue
 		}
+		if isConnected {
+			p.client = connection.newClient
+		}
+		connectionEvent := &GamePlayerConnectionEvent{PlayerIndex: index, IsConnected: isConnected}
+		g.room.broadcastEvent(connectionEvent, nil)
+		if isConnected {
+			p.sendEvent(g.newPlayersEvent(index))
+			gameStateEvent := GameStateEvent{}
+			gameStateEvent.GameStateInfo = g.getGameStateInfo(p)
+			p.sendEvent(gameStateEvent)
+		}
+		return
 	}
-	return nil
 }
 
-func (g *Game) getPlayerIndex(player *Player) int {
+func (g *Game) onClientRemoved(client ClientSender) {
 	for index, p := range g.players {
-		if p.client.Id() == player.client.Id() {
-			return index
+		if p.client.Id() == client.Id() && g.getPlayerState(index).isActive {
+			g.onActivePlayerLeft(index, false)
+			return
 		}
 	}
-	return -1
 }
 
-func (g *Game) hasBattlegroundSameValue(card *Card) bool {
-	for _, c := range g.battleground {
-		if c.Value == card.Value {
-			return true
-		}
+// Restarts clock for the player whose move the game is waiting for
+func (g *Game) restartMoveTimer() {
+	waitingPlayerIndex := -1
+	if g.status == GameStatusPlaying {
+		waitingPlayerIndex = g.state.getWaitingPlayerIndex()
 	}
-	return false
+	g.clock.restart(waitingPlayerIndex, g.moveTimeouts)
 }
 
-func (g *Game) hasDefendingCardsSameValue(card *Card) bool {
-	for _, c := range g.defendingCards {
-		if c.Value == card.Value {
-			return true
-		}
+// Makes rule-safe move for the player who ran out of time: defender picks up, attacker completes
+// or leads with the lowest card
+func (g *Game) onMoveTimeout(playerIndex int) {
+	if g.status != GameStatusPlaying {
+		return
 	}
-	return false
-}
+	s := g.state
+	log.Printf("player %d ran out of time", playerIndex)
 
-func (g *Game) hasBattlegroundCard(card *Card) bool {
-	for _, c := range g.battleground {
-		if c.equals(card) {
-			return true
-		}
+	if playerIndex == s.defenderIndex && len(s.defendingCards) < len(s.battleground) && s.canPlayerPickUp(playerIndex) {
+		g.applyAction(newPickUpAction(playerIndex))
+	} else if s.canPlayerComplete(playerIndex) {
+		g.applyAction(newCompleteAction(playerIndex))
+	} else if len(s.battleground) == 0 && s.canPlayerAttack(playerIndex) {
+		g.applyAction(newAttackAction(playerIndex, g.findLowestCard(s.players[playerIndex].cards)))
 	}
-	return false
 }
 
-func (g *Game) areAllAttackersCompleted() bool {
-	for _, p := range g.players {
-		if p.IsActive == true && g.getPlayerIndex(p) != g.defenderIndex && !p.IsCompleted {
-			return false
+// Returns the lowest card preferring non-trump cards
+func (g *Game) findLowestCard(cards []*Card) *Card {
+	trumpSuit := g.state.trumpSuit
+	var lowestCard *Card
+	for _, c := range cards {
+		if lowestCard == nil {
+			lowestCard = c
+			continue
 		}
-	}
-	return true
-}
-
-func (g *Game) areAllPlayersCompleted() bool {
-	for _, p := range g.players {
-		isDefenderAndPickUp := g.getPlayerIndex(p) == g.defenderIndex && g.defenderPickUp
-		if p.IsActive && !p.IsCompleted && !isDefenderAndPickUp {
-			return false
+		if lowestCard.Suit == trumpSuit && c.Suit != trumpSuit {
+			lowestCard = c
+			continue
+		}
+		isSameSuitType := (lowestCard.Suit == trumpSuit) == (c.Suit == trumpSuit)
+		if isSameSuitType && c.getValueIndex() < lowestCard.getValueIndex() {
+			lowestCard = c
 		}
 	}
-	return true
+	return lowestCard
 }
 
-func (g *Game) getActivePlayers() (players []*Player) {
+func (g *Game) findPlayerOfClient(client *Client) *Player {
 	for _, p := range g.players {
-		if p.IsActive {
-			players = append(players, p)
+		if p.client.Id() == client.Id() {
+			return p
 		}
 	}
-	return
-}
-
-func (g *Game) getActivePlayersNum() int {
-	return len(g.getActivePlayers())
+	return nil
 }
 
-func (g *Game) adjustPlayerIndex(index int) int {
-	activePlayersNum := g.getActivePlayersNum()
-	if activePlayersNum < 2 {
+func (g *Game) getPlayerIndex(player *Player) int {
+	if player == nil {
 		return -1
 	}
-
-	index = index % len(g.players)
-	if !g.players[index].IsActive {
-		return g.adjustPlayerIndex(index + 1)
-	}
-
-	return index
-}
-
-func (g *Game) getBattlegroundCardIndex(card *Card) int {
-	for index, c := range g.battleground {
-		if c.equals(card) {
+	for index, p := range g.players {
+		if p.client.Id() == player.client.Id() {
 			return index
 		}
 	}
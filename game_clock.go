@@ -0,0 +1,103 @@
+package main
+
+import (
+	"time"
+)
+
+// GameClock counts time of the player whose move is awaited.
+// Each move has its own time; when it is over, time bank of the player is used.
+type GameClock struct {
+	moveDuration       time.Duration
+	timeBanks          map[int]time.Duration
+	timer              *time.Timer
+	waitingPlayerIndex int
+	startedAt          time.Time
+	// Increases with each restart, so timeouts of previous moves are ignored
+	turn int
+}
+
+// MoveTimeout is sent by the clock when the player ran out of time on the turn
+type MoveTimeout struct {
+	playerIndex int
+	turn        int
+}
+
+func newGameClock(rules *GameRules, playersNum int) *GameClock {
+	timeBanks := make(map[int]time.Duration, 0)
+	for i := 0; i < playersNum; i++ {
+		timeBanks[i] = time.Second * time.Duration(rules.TimeBankSeconds)
+	}
+	return &GameClock{
+		moveDuration:       time.Second * time.Duration(rules.MoveSeconds),
+		timeBanks:          timeBanks,
+		waitingPlayerIndex: -1,
+	}
+}
+
+// Stops clock of the previous waiting player and starts clock of the given player.
+// Negative index stops the clock only. The timer only sends the timeout to the game, which checks that the turn is current.
+func (c *GameClock) restart(waitingPlayerIndex int, timeouts chan<- *MoveTimeout) {
+	c.stop()
+	c.turn++
+
+	if waitingPlayerIndex < 0 {
+		return
+	}
+
+	c.waitingPlayerIndex = waitingPlayerIndex
+	c.startedAt = time.Now()
+	timeout := &MoveTimeout{playerIndex: waitingPlayerIndex, turn: c.turn}
+	c.timer = time.AfterFunc(c.moveDuration+c.timeBanks[waitingPlayerIndex], func() {
+		select {
+		case timeouts <- timeout:
+		default:
+			// The game has not handled the previous timeout yet
+		}
+	})
+}
+
+// Checks if the timeout is of the move which the game is waiting for now
+func (c *GameClock) isCurrentTimeout(timeout *MoveTimeout) bool {
+	return timeout.turn == c.turn && timeout.playerIndex == c.waitingPlayerIndex
+}
+
+func (c *GameClock) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.waitingPlayerIndex >= 0 {
+		c.timeBanks[c.waitingPlayerIndex] = c.getTimeBankLeft(c.waitingPlayerIndex)
+		c.waitingPlayerIndex = -1
+	}
+}
+
+func (c *GameClock) getOvertime() time.Duration {
+	overtime := time.Since(c.startedAt) - c.moveDuration
+	if overtime < 0 {
+		return 0
+	}
+	return overtime
+}
+
+func (c *GameClock) getMoveTimeLeft() time.Duration {
+	if c.waitingPlayerIndex < 0 {
+		return 0
+	}
+	moveTimeLeft := c.moveDuration - time.Since(c.startedAt)
+	if moveTimeLeft < 0 {
+		return 0
+	}
+	return moveTimeLeft
+}
+
+func (c *GameClock) getTimeBankLeft(playerIndex int) time.Duration {
+	timeBank := c.timeBanks[playerIndex]
+	if playerIndex == c.waitingPlayerIndex {
+		timeBank -= c.getOvertime()
+	}
+	if timeBank < 0 {
+		return 0
+	}
+	return timeBank
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameClockUsesTimeBankAfterMoveTime(t *testing.T) {
+	rules := newGameRules()
+	rules.MoveSeconds = 0
+	rules.TimeBankSeconds = 10
+	clock := newGameClock(rules, 2)
+
+	clock.restart(1, make(chan *MoveTimeout, 1))
+	clock.startedAt = time.Now().Add(-3 * time.Second)
+	clock.stop()
+
+	assert := assert.New(t)
+	assert.Equal(-1, clock.waitingPlayerIndex)
+	assert.Equal(7, int(clock.getTimeBankLeft(1).Round(time.Second).Seconds()))
+	assert.Equal(10, int(clock.getTimeBankLeft(0).Seconds()))
+}
+
+func TestGameClockSendsTimeoutOfCurrentTurn(t *testing.T) {
+	rules := newGameRules()
+	rules.MoveSeconds = 0
+	clock := newGameClock(rules, 2)
+	timeouts := make(chan *MoveTimeout, 1)
+
+	clock.restart(1, timeouts)
+	timeout := <-timeouts
+
+	assert := assert.New(t)
+	assert.True(clock.isCurrentTimeout(timeout))
+	clock.restart(0, make(chan *MoveTimeout, 1))
+	assert.False(clock.isCurrentTimeout(timeout), "the player moved before the timeout was handled")
+}
+
+func TestMoveTimeoutDefenderPicksUp(t *testing.T) {
+	game := newTestGame(
+		[]*Card{{"7", "♥"}, {"8", "♥"}},
+		[]*Card{{"9", "♦"}, {"10", "♦"}},
+	)
+	game.applyAction(newAttackAction(0, &Card{"7", "♥"}))
+
+	assert := assert.New(t)
+	assert.Equal(1, game.state.getWaitingPlayerIndex())
+
+	game.onMoveTimeout(1)
+	assert.True(game.state.defenderPickUp)
+	assert.Equal(0, game.state.getWaitingPlayerIndex())
+}
+
+func TestMoveTimeoutAttackerCompletes(t *testing.T) {
+	game := newTestGame(
+		[]*Card{{"7", "♥"}, {"8", "♥"}},
+		[]*Card{{"9", "♥"}, {"10", "♦"}},
+		[]*Card{{"J", "♣"}, {"Q", "♣"}},
+	)
+	game.applyAction(newAttackAction(0, &Card{"7", "♥"}))
+	game.applyAction(newDefendAction(1, &Card{"7", "♥"}, &Card{"9", "♥"}))
+
+	assert := assert.New(t)
+	assert.Equal(0, game.state.getWaitingPlayerIndex())
+
+	game.onMoveTimeout(0)
+	assert.True(game.state.players[0].isCompleted)
+	assert.Equal(2, game.state.getWaitingPlayerIndex())
+}
+
+func TestMoveTimeoutAttackerLeadsWithLowestCard(t *testing.T) {
+	game := newTestGame(
+		[]*Card{{"7", "♠"}, {"8", "♥"}},
+		[]*Card{{"9", "♥"}, {"10", "♦"}},
+	)
+
+	game.onMoveTimeout(0)
+	assert.Equal(t, []*Card{{"8", "♥"}}, game.state.battleground)
+}
@@ -3,23 +3,44 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 )
 
 // GameFileLogger is implementation of GameLogger which stores logs in files
 type GameFileLogger struct {
+	*gameLogFiles
+}
+
+// BufferedGameLogger is GameLogger which writes logs in background
+type BufferedGameLogger interface {
+	GameLogger
+	// Blocks until logs of all stopped games are written
+	Wait()
+}
+
+// Buffers entries of running games and appends them to files when games stop
+type gameLogFiles struct {
 	dir         string
+	extension   string
 	errCallback func(err error)
 	bufferChans map[string]chan string
 	stopChans   map[string]chan bool
+	chansMutex  sync.Mutex
+	writings    sync.WaitGroup
 }
 
 // NewGameFileLogger constructor for GameFileLogger
 func NewGameFileLogger(dir string, errCallback func(err error)) *GameFileLogger {
+	return &GameFileLogger{newGameLogFiles(dir, ".log", errCallback)}
+}
+
+func newGameLogFiles(dir string, extension string, errCallback func(err error)) *gameLogFiles {
 	_ = os.MkdirAll(dir, 0777)
-	return &GameFileLogger{
+	return &gameLogFiles{
 		dir:         dir,
+		extension:   extension,
 		errCallback: errCallback,
 		bufferChans: make(map[string]chan string, 0),
 		stopChans:   make(map[string]chan bool, 0),
@@ -28,67 +49,198 @@ func NewGameFileLogger(dir string, errCallback func(err error)) *GameFileLogger
 
 // LogGameBegins starts recording log and adds entry about beginning the game
 func (l *GameFileLogger) LogGameBegins(game *Game) {
-	l.bufferChans[game.id] = make(chan string)
-	l.stopChans[game.id] = make(chan bool)
-	go l.writeLoop(game.id)
+	l.startWriteLoop(game.id)
 
-	lines := fmt.Sprintf("ENTRY Game begins. ID=%s\n", game.id)
+	lines := fmt.Sprintf(
+		"ENTRY Game begins. ID=%s; deckHash=%s;\n",
+		game.id,
+		game.deckCommitment.hash,
+	)
 	lines += getPlayersNames(game.players) + "\n"
+	lines += getRulesAsLine(game.rules) + "\n"
 	lines += getCurrentStateAsLines(game)
-	l.bufferChans[game.id] <- lines
+	l.add(game.id, lines)
 }
 
 // LogPlayerActionAttack adds entry about attack
-func (l *GameFileLogger) LogPlayerActionAttack(game *Game, data AttackActionData) {
+func (l *GameFileLogger) LogPlayerActionAttack(game *Game, player *Player, data AttackActionData) {
 	lines := fmt.Sprintf("ENTRY Attack. card=%s%s;\n", data.Card.Value, data.Card.Suit)
 	lines += getCurrentStateAsLines(game)
-	l.bufferChans[game.id] <- lines
+	l.add(game.id, lines)
 }
 
 // LogPlayerActionDefend adds entry about defense
-func (l *GameFileLogger) LogPlayerActionDefend(game *Game, data DefendActionData) {
+func (l *GameFileLogger) LogPlayerActionDefend(game *Game, player *Player, data DefendActionData) {
 	lines := fmt.Sprintf(
-		"Defend. attackingCard=%s%s; defendingCard=%s%s\n",
+		"ENTRY Defend. attackingCard=%s%s; defendingCard=%s%s\n",
 		data.AttackingCard.Value,
 		data.AttackingCard.Suit,
 		data.DefendingCard.Value,
 		data.DefendingCard.Suit,
 	)
 	lines += getCurrentStateAsLines(game)
-	l.bufferChans[game.id] <- lines
+	l.add(game.id, lines)
+}
+
+// LogPlayerActionTransfer adds entry about transfer
+func (l *GameFileLogger) LogPlayerActionTransfer(game *Game, player *Player, data TransferActionData) {
+	lines := fmt.Sprintf("ENTRY Transfer. card=%s%s;\n", data.Card.Value, data.Card.Suit)
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
 }
 
 // LogPlayerActionPickUp adds entry about pick up
-func (l *GameFileLogger) LogPlayerActionPickUp(game *Game) {
+func (l *GameFileLogger) LogPlayerActionPickUp(game *Game, player *Player) {
 	lines := fmt.Sprintf("ENTRY PickUp.\n")
 	lines += getCurrentStateAsLines(game)
-	l.bufferChans[game.id] <- lines
+	l.add(game.id, lines)
 }
 
 // LogPlayerActionComplete adds entry about completing a round
-func (l *GameFileLogger) LogPlayerActionComplete(game *Game) {
+func (l *GameFileLogger) LogPlayerActionComplete(game *Game, player *Player) {
 	lines := fmt.Sprintf("ENTRY Complete.\n")
 	lines += getCurrentStateAsLines(game)
-	l.bufferChans[game.id] <- lines
+	l.add(game.id, lines)
+}
+
+// LogPlayerActionRejected adds entry about action which is not allowed by rules
+func (l *GameFileLogger) LogPlayerActionRejected(game *Game, player *Player, actionName string, data interface{}) {
+	lines := fmt.Sprintf(
+		"ENTRY Action rejected. player=%d; action=%s;%s\n",
+		game.getPlayerIndex(player),
+		actionName,
+		getActionDataAsString(data),
+	)
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+}
+
+// LogRoundEnds adds entry about ending a round with the state of the new round
+func (l *GameFileLogger) LogRoundEnds(game *Game, wasAttackSuccessful bool) {
+	lines := fmt.Sprintf("ENTRY Round ends. wasAttackSuccessful=%t;\n", wasAttackSuccessful)
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+}
+
+// LogPlayerLeft adds entry about active player who left the game
+func (l *GameFileLogger) LogPlayerLeft(game *Game, playerIndex int, isAfk bool) {
+	lines := fmt.Sprintf("ENTRY Player left. player=%d; isAfk=%t;\n", playerIndex, isAfk)
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+}
+
+// LogLatePlayerJoin adds entry about spectator who joined the running game
+func (l *GameFileLogger) LogLatePlayerJoin(game *Game, player *Player) {
+	lines := fmt.Sprintf("ENTRY Late player join. player=%d; name=%s;\n", game.getPlayerIndex(player), escapeGameLogValue(player.Name))
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+}
+
+// LogGameDeleted adds entry about deleting the game which is ended by its loop, the entry is appended to the file
+func (l *GameFileLogger) LogGameDeleted(game *Game) {
+	l.startWriteLoop(game.id)
+	lines := fmt.Sprintf("ENTRY Game deleted.\n")
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+	l.stop(game.id)
+}
+
+// LogGameDraw adds entry about draw
+func (l *GameFileLogger) LogGameDraw(game *Game, data *GameDrawEvent) {
+	lines := fmt.Sprintf("ENTRY Draw. players=%s;\n", indexesToString(data.PlayersIndexes))
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
 }
 
 // LogGameEnds adds entry about ending game and writes file with entries
-func (l *GameFileLogger) LogGameEnds(game *Game, hasLoser bool, loserIndex int) {
-	lines := fmt.Sprintf("ENTRY Game ends. hasLoser=%t;loserIndex=%d\n", hasLoser, loserIndex)
+func (l *GameFileLogger) LogGameEnds(game *Game, data *GameEndEvent) {
+	lines := fmt.Sprintf(
+		"ENTRY Game ends. reason=%s;hasLoser=%t;loserIndex=%d;loserTeam=%d;placings=%s;deckOrder=%s;deckSalt=%s;seed=%d;\n",
+		data.Reason,
+		data.HasLoser,
+		data.LoserIndex,
+		data.LoserTeam,
+		placingsToString(data.Placings),
+		data.DeckOrder,
+		data.DeckSalt,
+		data.Seed,
+	)
 	lines += getCurrentStateAsLines(game)
-	l.bufferChans[game.id] <- lines
-	l.stopChans[game.id] <- true
+	l.add(game.id, lines)
+	l.stop(game.id)
 }
 
-func (l *GameFileLogger) write(gameId string, contents string) error {
-	currentTime := time.Now()
-	dir := l.dir + "/" + fmt.Sprintf("%d%02d", currentTime.Year(), currentTime.Month())
+// LogGameSuspended adds entry about saving the game to snapshot and writes file with entries
+func (l *GameFileLogger) LogGameSuspended(game *Game) {
+	lines := fmt.Sprintf("ENTRY Game suspended.\n")
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+	l.stop(game.id)
+}
+
+// LogGameRestored continues recording log of the game which was suspended
+func (l *GameFileLogger) LogGameRestored(game *Game) {
+	l.startWriteLoop(game.id)
+
+	lines := fmt.Sprintf("ENTRY Game restored.\n")
+	lines += getCurrentStateAsLines(game)
+	l.add(game.id, lines)
+}
+
+// Wait blocks until logs of all stopped games are written to files
+func (l *gameLogFiles) Wait() {
+	l.writings.Wait()
+}
+
+// Starts recording log of the game, the previous log of the game is appended to the file first
+func (l *gameLogFiles) startWriteLoop(gameId string) {
+	bufferChan, stopChan := l.getChans(gameId)
+	if stopChan != nil {
+		<-stopChan
+	}
+
+	bufferChan = make(chan string)
+	stopChan = make(chan bool)
+	l.chansMutex.Lock()
+	l.bufferChans[gameId] = bufferChan
+	l.stopChans[gameId] = stopChan
+	l.chansMutex.Unlock()
+	l.writings.Add(1)
+	go l.writeLoop(gameId, bufferChan, stopChan)
+}
+
+// Entries of the game which log is not recorded, e.g. rejected actions after the end, are dropped
+func (l *gameLogFiles) add(gameId string, contents string) {
+	bufferChan, stopChan := l.getChans(gameId)
+	if bufferChan == nil {
+		return
+	}
+	select {
+	case bufferChan <- contents:
+	case <-stopChan:
+	}
+}
+
+func (l *gameLogFiles) stop(gameId string) {
+	_, stopChan := l.getChans(gameId)
+	stopChan <- true
+}
+
+func (l *gameLogFiles) getChans(gameId string) (chan string, chan bool) {
+	l.chansMutex.Lock()
+	defer l.chansMutex.Unlock()
+	return l.bufferChans[gameId], l.stopChans[gameId]
+}
+
+// Appends contents to the log file of the game, the file is in dir of the month when the game was created
+func (l *gameLogFiles) write(gameId string, contents string) error {
+	dir := l.dir + "/" + gameId[:6]
 	err := os.MkdirAll(dir, 0777)
 	if err != nil {
 		return err
 	}
 
-	f, err := os.Create(dir + "/" + gameId + ".log")
+	f, err := os.OpenFile(dir+"/"+gameId+l.extension, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		return err
 	}
@@ -103,62 +255,91 @@ func (l *GameFileLogger) write(gameId string, contents string) error {
 	return nil
 }
 
-func (l *GameFileLogger) writeLoop(gameId string) {
+// Adds the game to the index of games when its log is written up to the end
+func (l *gameLogFiles) addToIndex(gameId string) error {
+	history, err := readGameHistory(l.dir, gameId)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return addGameHistoryToIndex(l.dir, history)
+}
+
+func (l *gameLogFiles) writeLoop(gameId string, bufferChan chan string, stopChan chan bool) {
 	contents := ""
 
 	defer func() {
-		close(l.stopChans[gameId])
+		defer l.writings.Done()
 
 		err := l.write(gameId, contents)
 		if err != nil {
 			l.errCallback(err)
+		} else if err := l.addToIndex(gameId); err != nil {
+			l.errCallback(err)
 		}
+		// Closed stop channel means that the file is written and later entries are dropped
+		close(stopChan)
 	}()
 
 	for {
 		select {
-		case lines, ok := <-l.bufferChans[gameId]:
-			if !ok {
-				return
-			}
+		case lines := <-bufferChan:
 			contents += lines
-		case <-l.stopChans[gameId]:
-			close(l.bufferChans[gameId])
+		case <-stopChan:
+			return
 		}
 	}
 }
 
 func getCurrentStateAsLines(game *Game) string {
 	line := "State:\n"
-	line += fmt.Sprintf("players=%d %s", len(game.players), getPlayersCards(game.players))
+	state := game.state
+	line += fmt.Sprintf("players=%d %s", len(game.players), getPlayersCards(game))
 	line += "\n"
-	line += fmt.Sprintf("deck=%d:%s;", len(game.deck.cards), cardsToString(game.deck.cards))
-	line += fmt.Sprintf("battleground=%d:%s;", len(game.battleground), cardsToString(game.battleground))
-	line += fmt.Sprintf("attacker=%d;", game.attackerIndex)
-	line += fmt.Sprintf("defender=%d;", game.defenderIndex)
-	line += fmt.Sprintf("trump=%s%s;", game.trumpCard.Value, game.trumpCard.Suit)
+	line += fmt.Sprintf("deck=%d:%s;", len(state.deck.cards), cardsToString(state.deck.cards))
+	line += fmt.Sprintf("battleground=%d:%s;", len(state.battleground), cardsToString(state.battleground))
+	line += fmt.Sprintf("attacker=%d;", state.attackerIndex)
+	line += fmt.Sprintf("defender=%d;", state.defenderIndex)
+	line += fmt.Sprintf("trump=%s%s;", state.trumpCard.Value, state.trumpCard.Suit)
 	line += "\n"
 	return line
 }
 
-func getPlayersCards(players []*Player) string {
+func getPlayersCards(game *Game) string {
 	str := ""
 
-	for index, player := range players {
+	for index, player := range game.players {
 		if index != 0 {
 			str += " "
 		}
 		isBot := "human"
-		if strings.HasPrefix(player.Name, "bot-") {
+		if player.isBot() {
 			isBot = "bot"
 		}
 
-		str += fmt.Sprintf("P=%d(%s):%s;", index, isBot, cardsToString(player.cards))
+		str += fmt.Sprintf("P=%d(%s):%s;", index, isBot, cardsToString(game.getPlayerState(index).cards))
 	}
 
 	return str
 }
 
+func getRulesAsLine(rules *GameRules) string {
+	return fmt.Sprintf(
+		"rules=deckSize:%d;handSize:%d;firstRoundAttackLimit:%d;maxCardsPerBout:%d;throwIn:%s;transfer:%t;teamPlay:%t;moveSeconds:%d;timeBankSeconds:%d;",
+		rules.DeckSize,
+		rules.HandSize,
+		rules.FirstRoundAttackLimit,
+		rules.MaxCardsPerBout,
+		rules.ThrowIn,
+		rules.Transfer,
+		rules.TeamPlay,
+		rules.MoveSeconds,
+		rules.TimeBankSeconds,
+	)
+}
+
 func getPlayersNames(players []*Player) string {
 	str := ""
 
@@ -167,12 +348,65 @@ func getPlayersNames(players []*Player) string {
 			str += " "
 		}
 
-		str += fmt.Sprintf("P%d=%s;", index, player.Name)
+		str += fmt.Sprintf("P%d=%s;", index, escapeGameLogValue(player.Name))
+		if player.Team != TeamNone {
+			str += fmt.Sprintf("T%d=%d;", index, player.Team)
+		}
 	}
 
 	return str
 }
 
+// Nicknames can contain delimiters of parameters and lines, they are escaped like in URLs
+var gameLogValueEscaper = strings.NewReplacer("%", "%25", ";", "%3B", "=", "%3D", "\n", "%0A", "\r", "%0D")
+var gameLogValueUnescaper = strings.NewReplacer("%25", "%", "%3B", ";", "%3D", "=", "%0A", "\n", "%0D", "\r")
+
+func escapeGameLogValue(value string) string {
+	return gameLogValueEscaper.Replace(value)
+}
+
+func unescapeGameLogValue(value string) string {
+	return gameLogValueUnescaper.Replace(value)
+}
+
+// Returns cards of the action as parameters of the entry
+func getActionDataAsString(data interface{}) string {
+	switch d := data.(type) {
+	case AttackActionData:
+		return fmt.Sprintf(" card=%s;", cardToString(d.Card))
+	case DefendActionData:
+		return fmt.Sprintf(" attackingCard=%s; defendingCard=%s;", cardToString(d.AttackingCard), cardToString(d.DefendingCard))
+	case TransferActionData:
+		return fmt.Sprintf(" card=%s;", cardToString(d.Card))
+	}
+	return ""
+}
+
+func cardToString(card *Card) string {
+	if card == nil {
+		return ""
+	}
+	return card.Value + card.Suit
+}
+
+func placingsToString(placings []*GamePlacing) string {
+	placingsStrings := make([]string, 0)
+	for _, placing := range placings {
+		placingsStrings = append(placingsStrings, fmt.Sprintf("%d:%d", placing.PlayerIndex, placing.Place))
+	}
+
+	return strings.Join(placingsStrings, ",")
+}
+
+func indexesToString(indexes []int) string {
+	indexesStrings := make([]string, 0)
+	for _, index := range indexes {
+		indexesStrings = append(indexesStrings, strconv.Itoa(index))
+	}
+
+	return strings.Join(indexesStrings, ",")
+}
+
 func cardsToString(cards []*Card) string {
 	str := ""
 
@@ -0,0 +1,659 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of listing of games
+const (
+	GameHistoryDefaultLimit = 20
+	GameHistoryMaxLimit     = 100
+
+	// Summaries of finished games of a month are appended to the file in the dir of the month
+	GameHistoryIndexFileName = "index.jsonl"
+)
+
+// Index files are written by loggers and read by API at the same time
+var gameHistoryIndexMutex sync.Mutex
+
+var (
+	gameIdRegexp          = regexp.MustCompile(`^\d{8}_\d{6}_\d+$`)
+	gameLogMonthRegexp    = regexp.MustCompile(`^\d{6}$`)
+	gameLogPlayerRegexp   = regexp.MustCompile(`P=(\d+)\((human|bot)\):([^;]*);`)
+	gameLogPlayerIdRegexp = regexp.MustCompile(`^P(\d+)$`)
+	gameLogTeamIdRegexp   = regexp.MustCompile(`^T(\d+)$`)
+)
+
+// GameHistorySummary contains short info of a game from the log
+type GameHistorySummary struct {
+	Id         string                `json:"id"`
+	Month      string                `json:"month"`
+	Players    []*GameHistoryPlayer  `json:"players"`
+	HasBots    bool                  `json:"hasBots"`
+	IsEnded    bool                  `json:"isEnded"`
+	IsDeleted  bool                  `json:"isDeleted"`
+	Reason     string                `json:"reason"`
+	HasLoser   bool                  `json:"hasLoser"`
+	LoserIndex int                   `json:"loserIndex"`
+	LoserName  string                `json:"loserName"`
+	Placings   []*GameHistoryPlacing `json:"placings"`
+}
+
+// GameHistoryPlayer contains a player of a game from the log
+type GameHistoryPlayer struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Team  int    `json:"team"`
+	IsBot bool   `json:"isBot"`
+	// Spectator who joined the running game without cards
+	IsLate bool `json:"isLate,omitempty"`
+}
+
+// GameHistoryPlacing contains the place of a player
+type GameHistoryPlacing struct {
+	PlayerIndex int `json:"playerIndex"`
+	Place       int `json:"place"`
+}
+
+// GameHistory contains a game with all logged entries
+type GameHistory struct {
+	GameHistorySummary
+	Seed     string              `json:"seed"`
+	DeckHash string              `json:"deckHash"`
+	Rules    map[string]string   `json:"rules"`
+	Entries  []*GameHistoryEntry `json:"entries"`
+}
+
+// GameHistoryEntry contains an event of the game and the state after it
+type GameHistoryEntry struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"`
+	// Time, actor and data of the action are known from JSON logs only
+	Time       *time.Time        `json:"time,omitempty"`
+	ActorIndex *int              `json:"actorIndex,omitempty"`
+	Action     json.RawMessage   `json:"action,omitempty"`
+	State      *GameHistoryState `json:"state"`
+}
+
+// GameHistoryState contains cards of the game after an entry
+type GameHistoryState struct {
+	PlayersCards [][]*Card `json:"playersCards"`
+	Deck         []*Card   `json:"deck"`
+	Battleground []*Card   `json:"battleground"`
+	// Defending cards are aligned with battleground, they are not known from text logs
+	DefendingCards  []*Card `json:"defendingCards,omitempty"`
+	DiscardPileSize int     `json:"discardPileSize,omitempty"`
+	AttackerIndex   int     `json:"attackerIndex"`
+	DefenderIndex   int     `json:"defenderIndex"`
+	Trump           *Card   `json:"trump"`
+}
+
+// GameHistoryFilter contains conditions of listing games
+type GameHistoryFilter struct {
+	Month    string
+	Player   string
+	Loser    string
+	WithBots *bool
+	Limit    int
+}
+
+func (f *GameHistoryFilter) matches(s *GameHistorySummary) bool {
+	if f.WithBots != nil && *f.WithBots != s.HasBots {
+		return false
+	}
+	if f.Loser != "" && (!s.HasLoser || !strings.EqualFold(f.Loser, s.LoserName)) {
+		return false
+	}
+	if f.Player != "" {
+		for _, p := range s.Players {
+			if strings.EqualFold(f.Player, p.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isValidGameId(gameId string) bool {
+	return gameIdRegexp.MatchString(gameId)
+}
+
+// Reads the game from its log file in the dir of logs, the log can be written in any format.
+// Logs of running and suspended games are not found, because they show cards of players and the deck.
+func readGameHistory(dir string, gameId string) (*GameHistory, error) {
+	if !isValidGameId(gameId) {
+		return nil, os.ErrNotExist
+	}
+	history, err := readGameLog(dir, gameId)
+	if err != nil {
+		return nil, err
+	}
+	if !history.isFinished() {
+		return nil, os.ErrNotExist
+	}
+
+	return history, nil
+}
+
+func readGameLog(dir string, gameId string) (*GameHistory, error) {
+	f, err := os.Open(filepath.Join(dir, gameId[:6], gameId+".log"))
+	if os.IsNotExist(err) {
+		f, err = os.Open(filepath.Join(dir, gameId[:6], gameId+".jsonl"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseGameJsonLog(gameId, f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseGameLog(gameId, f)
+}
+
+// Checks if the log is written up to the end of the game
+func (h *GameHistory) isFinished() bool {
+	lastEntryName := h.Entries[len(h.Entries)-1].Name
+	return lastEntryName == "Game ends" || lastEntryName == "Game deleted"
+}
+
+// Returns the newest games matching the filter; months and games are read from the newest ones.
+// Summaries are read from indexes of months, so logs are not parsed on every request.
+func listGameHistory(dir string, filter *GameHistoryFilter) ([]*GameHistorySummary, error) {
+	months, err := getGameLogMonths(dir, filter.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*GameHistorySummary, 0)
+	for _, month := range months {
+		monthSummaries, err := readGameHistoryIndex(dir, month)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range monthSummaries {
+			if len(summaries) >= filter.Limit {
+				return summaries, nil
+			}
+			if filter.matches(summary) {
+				summaries = append(summaries, summary)
+			}
+		}
+	}
+
+	return summaries, nil
+}
+
+// Adds summary of the finished game to the index of its month, the index is built first if there is no one
+func addGameHistoryToIndex(dir string, history *GameHistory) error {
+	gameHistoryIndexMutex.Lock()
+	defer gameHistoryIndexMutex.Unlock()
+
+	indexPath := filepath.Join(dir, history.Month, GameHistoryIndexFileName)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		_, err := buildGameHistoryIndex(dir, history.Month)
+		return err
+	}
+
+	line, err := json.Marshal(&history.GameHistorySummary)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(indexPath, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+
+	return err
+}
+
+// Returns summaries of finished games of the month from the newest ones.
+// The index is built from logs of the month if there is no one, e.g. for logs written by older versions.
+func readGameHistoryIndex(dir string, month string) ([]*GameHistorySummary, error) {
+	gameHistoryIndexMutex.Lock()
+	defer gameHistoryIndexMutex.Unlock()
+
+	f, err := os.Open(filepath.Join(dir, month, GameHistoryIndexFileName))
+	if os.IsNotExist(err) {
+		return buildGameHistoryIndex(dir, month)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Summary of the game is added again when the ended game is deleted, the last one is actual
+	summariesById := make(map[string]*GameHistorySummary, 0)
+	decoder := json.NewDecoder(f)
+	for {
+		var summary GameHistorySummary
+		err := decoder.Decode(&summary)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse index of games of %s: %s", month, err)
+		}
+		summariesById[summary.Id] = &summary
+	}
+
+	summaries := make([]*GameHistorySummary, 0, len(summariesById))
+	for _, summary := range summariesById {
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Id > summaries[j].Id
+	})
+
+	return summaries, nil
+}
+
+// Reads all logs of the month and writes summaries of finished games to the index
+func buildGameHistoryIndex(dir string, month string) ([]*GameHistorySummary, error) {
+	summaries := make([]*GameHistorySummary, 0)
+	contents := make([]byte, 0)
+	err := walkGameLogs(dir, month, func(gameId string) (bool, error) {
+		history, err := readGameHistory(dir, gameId)
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		line, err := json.Marshal(&history.GameHistorySummary)
+		if err != nil {
+			return false, err
+		}
+		summaries = append(summaries, &history.GameHistorySummary)
+		contents = append(append(contents, line...), '\n')
+		return true, nil
+	})
+	if err != nil || len(summaries) == 0 {
+		return summaries, err
+	}
+
+	indexPath := filepath.Join(dir, month, GameHistoryIndexFileName)
+	tmpPath := indexPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, contents, 0666); err != nil {
+		return nil, err
+	}
+
+	return summaries, os.Rename(tmpPath, indexPath)
+}
+
+// Calls the callback with ids of logged games from the newest ones until it returns false or error.
+// Games of all months are walked when the month is empty.
+func walkGameLogs(dir string, month string, callback func(gameId string) (bool, error)) error {
+	months, err := getGameLogMonths(dir, month)
+	if err != nil {
+		return err
+	}
+
+	for _, month := range months {
+		files, err := ioutil.ReadDir(filepath.Join(dir, month))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		gameIds := make([]string, 0)
+		for _, f := range files {
+			gameId := strings.TrimSuffix(strings.TrimSuffix(f.Name(), ".log"), ".jsonl")
+			if !f.IsDir() && gameId != f.Name() && isValidGameId(gameId) {
+				gameIds = append(gameIds, gameId)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(gameIds)))
+
+		for _, gameId := range gameIds {
+			next, err := callback(gameId)
+			if err != nil || !next {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns months of logs from the newest ones, all months are returned when the month is empty
+func getGameLogMonths(dir string, month string) ([]string, error) {
+	months := make([]string, 0)
+	if month != "" {
+		return append(months, month), nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return months, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() && gameLogMonthRegexp.MatchString(f.Name()) {
+			months = append(months, f.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(months)))
+
+	return months, nil
+}
+
+// Parses the log written by GameFileLogger
+func parseGameLog(gameId string, r io.Reader) (*GameHistory, error) {
+	history := &GameHistory{
+		GameHistorySummary: GameHistorySummary{
+			Id:         gameId,
+			Month:      gameId[:6],
+			Players:    make([]*GameHistoryPlayer, 0),
+			LoserIndex: -1,
+			Placings:   make([]*GameHistoryPlacing, 0),
+		},
+		Rules:   make(map[string]string, 0),
+		Entries: make([]*GameHistoryEntry, 0),
+	}
+
+	var entry *GameHistoryEntry
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+		switch {
+		case lineNum == 2:
+			history.Players = parseGameLogPlayersNames(line)
+		case strings.HasPrefix(line, "rules="):
+			for key, value := range parseGameLogParams(strings.TrimPrefix(line, "rules=")) {
+				history.Rules[key] = value
+			}
+		case line == "State:":
+		case strings.HasPrefix(line, "players="):
+			if entry == nil {
+				return nil, errors.New("state without entry in game log")
+			}
+			entry.State = &GameHistoryState{PlayersCards: make([][]*Card, 0)}
+			for _, matches := range gameLogPlayerRegexp.FindAllStringSubmatch(line, -1) {
+				index, _ := strconv.Atoi(matches[1])
+				if index < len(history.Players) && matches[2] == "bot" {
+					history.Players[index].IsBot = true
+					history.HasBots = true
+				}
+				entry.State.PlayersCards = append(entry.State.PlayersCards, parseCardsString(matches[3]))
+			}
+		case strings.HasPrefix(line, "deck="):
+			if entry == nil || entry.State == nil {
+				return nil, errors.New("state without entry in game log")
+			}
+			params := parseGameLogParams(line)
+			entry.State.Deck = parseCardsString(getCountedCards(params["deck"]))
+			entry.State.Battleground = parseCardsString(getCountedCards(params["battleground"]))
+			entry.State.AttackerIndex, _ = strconv.Atoi(params["attacker"])
+			entry.State.DefenderIndex, _ = strconv.Atoi(params["defender"])
+			if trump := parseCardsString(params["trump"]); len(trump) > 0 {
+				entry.State.Trump = trump[0]
+			}
+		case line == "":
+		default:
+			entry = parseGameLogEntry(line)
+			history.Entries = append(history.Entries, entry)
+			history.applyEntry(entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(history.Entries) == 0 {
+		return nil, errors.New("game log has no entries")
+	}
+
+	return history, nil
+}
+
+// Parses the log written by GameJsonLogger
+func parseGameJsonLog(gameId string, r io.Reader) (*GameHistory, error) {
+	history := &GameHistory{
+		GameHistorySummary: GameHistorySummary{
+			Id:         gameId,
+			Month:      gameId[:6],
+			Players:    make([]*GameHistoryPlayer, 0),
+			LoserIndex: -1,
+			Placings:   make([]*GameHistoryPlacing, 0),
+		},
+		Rules:   make(map[string]string, 0),
+		Entries: make([]*GameHistoryEntry, 0),
+	}
+
+	decoder := json.NewDecoder(r)
+	for {
+		var record GameJsonLogRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Version > GameJsonLogVersion {
+			return nil, fmt.Errorf("unsupported version of game log: %d", record.Version)
+		}
+
+		recordTime := record.Time
+		entry := &GameHistoryEntry{
+			Name:       record.Entry,
+			Params:     make(map[string]string, 0),
+			Time:       &recordTime,
+			ActorIndex: record.ActorIndex,
+			Action:     record.Action,
+			State:      record.State,
+		}
+		if record.Players != nil {
+			history.Players = record.Players
+			for _, p := range record.Players {
+				history.HasBots = history.HasBots || p.IsBot
+			}
+		}
+		if record.Rules != nil {
+			history.Rules = getRulesAsMap(record.Rules)
+		}
+		if record.Seed != nil {
+			entry.Params["seed"] = strconv.FormatInt(*record.Seed, 10)
+		}
+		if record.DeckHash != "" {
+			entry.Params["deckHash"] = record.DeckHash
+		}
+		// Parameters of events are the same as in text logs
+		switch record.Entry {
+		case "Game ends":
+			var endEvent GameEndEvent
+			if err := json.Unmarshal(record.Action, &endEvent); err != nil {
+				return nil, err
+			}
+			entry.Params["reason"] = endEvent.Reason
+			entry.Params["hasLoser"] = strconv.FormatBool(endEvent.HasLoser)
+			entry.Params["loserIndex"] = strconv.Itoa(endEvent.LoserIndex)
+			entry.Params["placings"] = placingsToString(endEvent.Placings)
+		case "Round ends":
+			var roundEnd GameJsonLogRoundEnd
+			if err := json.Unmarshal(record.Action, &roundEnd); err != nil {
+				return nil, err
+			}
+			entry.Params["wasAttackSuccessful"] = strconv.FormatBool(roundEnd.WasAttackSuccessful)
+		case "Player left":
+			var playerLeft GamePlayerLeftEvent
+			if err := json.Unmarshal(record.Action, &playerLeft); err != nil {
+				return nil, err
+			}
+			entry.Params["player"] = strconv.Itoa(playerLeft.PlayerIndex)
+			entry.Params["isAfk"] = strconv.FormatBool(playerLeft.IsAfk)
+		case "Action rejected":
+			var rejected GameJsonLogRejectedAction
+			if err := json.Unmarshal(record.Action, &rejected); err != nil {
+				return nil, err
+			}
+			entry.Params["action"] = rejected.Name
+		}
+		history.Entries = append(history.Entries, entry)
+		history.applyEntry(entry)
+	}
+	if len(history.Entries) == 0 {
+		return nil, errors.New("game log has no entries")
+	}
+
+	return history, nil
+}
+
+// Rules are shown as strings in the same way for all formats of logs
+func getRulesAsMap(rules *GameRules) map[string]string {
+	rulesMap := make(map[string]string, 0)
+	rulesJson, _ := json.Marshal(rules)
+	values := make(map[string]interface{}, 0)
+	_ = json.Unmarshal(rulesJson, &values)
+	for key, value := range values {
+		rulesMap[key] = fmt.Sprint(value)
+	}
+	return rulesMap
+}
+
+func (h *GameHistory) applyEntry(entry *GameHistoryEntry) {
+	switch entry.Name {
+	case "Game begins":
+		h.DeckHash = entry.Params["deckHash"]
+	case "Late player join":
+		player := &GameHistoryPlayer{IsLate: true}
+		if entry.Action != nil {
+			_ = json.Unmarshal(entry.Action, player)
+		} else {
+			player.Index, _ = strconv.Atoi(entry.Params["player"])
+			player.Name = unescapeGameLogValue(entry.Params["name"])
+		}
+		if player.Index == len(h.Players) {
+			h.Players = append(h.Players, player)
+		}
+	case "Game deleted":
+		h.IsDeleted = true
+	case "Game ends":
+		h.IsEnded = true
+		h.Seed = entry.Params["seed"]
+		h.Reason = entry.Params["reason"]
+		h.HasLoser = entry.Params["hasLoser"] == "true"
+		if h.HasLoser {
+			h.LoserIndex, _ = strconv.Atoi(entry.Params["loserIndex"])
+			if h.LoserIndex >= 0 && h.LoserIndex < len(h.Players) {
+				h.LoserName = h.Players[h.LoserIndex].Name
+			}
+		}
+		if entry.Params["placings"] != "" {
+			for _, placingStr := range strings.Split(entry.Params["placings"], ",") {
+				parts := strings.SplitN(placingStr, ":", 2)
+				if len(parts) != 2 {
+					continue
+				}
+				playerIndex, _ := strconv.Atoi(parts[0])
+				place, _ := strconv.Atoi(parts[1])
+				h.Placings = append(h.Placings, &GameHistoryPlacing{PlayerIndex: playerIndex, Place: place})
+			}
+		}
+	}
+}
+
+// Entry line looks like "ENTRY Attack. card=7♥;"
+func parseGameLogEntry(line string) *GameHistoryEntry {
+	line = strings.TrimPrefix(line, "ENTRY ")
+	entry := &GameHistoryEntry{Params: make(map[string]string, 0)}
+	dotIndex := strings.Index(line, ".")
+	if dotIndex < 0 {
+		entry.Name = line
+		return entry
+	}
+	entry.Name = line[:dotIndex]
+	entry.Params = parseGameLogParams(line[dotIndex+1:])
+	return entry
+}
+
+// Parses parameters like "key1=value1; key2=value2;"
+func parseGameLogParams(str string) map[string]string {
+	params := make(map[string]string, 0)
+	for _, pair := range strings.Split(str, ";") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) == 2 {
+			params[parts[0]] = parts[1]
+		} else if colonParts := strings.SplitN(parts[0], ":", 2); len(colonParts) == 2 {
+			params[colonParts[0]] = colonParts[1]
+		}
+	}
+	return params
+}
+
+// Names line looks like "P0=Alice; T0=1; P1=Bob; T1=2;"
+func parseGameLogPlayersNames(line string) []*GameHistoryPlayer {
+	players := make([]*GameHistoryPlayer, 0)
+	for _, pair := range strings.Split(line, ";") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if matches := gameLogPlayerIdRegexp.FindStringSubmatch(parts[0]); matches != nil {
+			index, _ := strconv.Atoi(matches[1])
+			players = append(players, &GameHistoryPlayer{Index: index, Name: unescapeGameLogValue(parts[1])})
+		} else if matches := gameLogTeamIdRegexp.FindStringSubmatch(parts[0]); matches != nil {
+			index, _ := strconv.Atoi(matches[1])
+			team, _ := strconv.Atoi(parts[1])
+			if index < len(players) {
+				players[index].Team = team
+			}
+		}
+	}
+	return players
+}
+
+// Removes the count of cards from "3:7♥8♥9♥"
+func getCountedCards(str string) string {
+	parts := strings.SplitN(str, ":", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// Parses cards written by cardsToString
+func parseCardsString(str string) []*Card {
+	cards := make([]*Card, 0)
+	value := ""
+	for _, r := range str {
+		s := string(r)
+		isSuit := false
+		for _, suit := range cardSuits {
+			if s == suit {
+				isSuit = true
+				break
+			}
+		}
+		if !isSuit {
+			value += s
+			continue
+		}
+		cards = append(cards, &Card{Value: value, Suit: s})
+		value = ""
+	}
+	return cards
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGameLogDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "durak_games")
+	if err != nil {
+		t.Fatalf("Cannot create temp dir: %s", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func newTestGameFileLogger(t *testing.T, dir string) *GameFileLogger {
+	return NewGameFileLogger(dir, func(err error) {
+		t.Errorf("Cannot write log: %s", err)
+	})
+}
+
+// Writes log of a short game where the bot loses
+func writeTestGameLog(t *testing.T, logger BufferedGameLogger) *Game {
+	game := newTestGame(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"10", "♠"}, {"8", "♣"}},
+	)
+	game.players[0].Name = "Alice"
+	game.players[1].Name = "bot-Bob"
+	game.players[1].client = &BotClient{nickname: "bot-Bob", incomingEvents: make(chan []byte, 10)}
+	game.state.deck = &Deck{cards: []*Card{{"6", "♠"}}}
+	deckCommitment, err := newDeckCommitment(game.state.deck)
+	if err != nil {
+		t.Fatalf("Cannot create deck commitment: %s", err)
+	}
+	game.deckCommitment = deckCommitment
+
+	logger.LogGameBegins(game)
+	attackData := AttackActionData{Card: &Card{"7", "♥"}}
+	game.state.play(GameAction{PlayerIndex: 0, Name: PlayerActionNameAttack, Data: attackData})
+	logger.LogPlayerActionAttack(game, game.players[0], attackData)
+	defendData := DefendActionData{AttackingCard: &Card{"7", "♥"}, DefendingCard: &Card{"10", "♠"}}
+	game.state.play(GameAction{PlayerIndex: 1, Name: PlayerActionNameDefend, Data: defendData})
+	logger.LogPlayerActionDefend(game, game.players[1], defendData)
+	logger.LogGameEnds(game, &GameEndEvent{
+		Reason:     "end",
+		HasLoser:   true,
+		LoserIndex: 1,
+		Placings:   []*GamePlacing{{PlayerIndex: 0, Place: 1}, {PlayerIndex: 1, Place: 2}},
+		DeckOrder:  deckCommitment.deckOrder,
+		DeckSalt:   deckCommitment.salt,
+		Seed:       game.seed,
+	})
+	logger.Wait()
+
+	return game
+}
+
+func TestReadGameHistory(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	game := writeTestGameLog(t, newTestGameFileLogger(t, dir))
+
+	history, err := readGameHistory(dir, game.id)
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(game.id[:6], history.Month)
+	assert.Equal(&GameHistoryPlayer{Index: 1, Name: "bot-Bob", IsBot: true}, history.Players[1])
+	assert.True(history.HasBots)
+	assert.True(history.IsEnded)
+	assert.Equal("bot-Bob", history.LoserName)
+	assert.Equal([]*GameHistoryPlacing{{PlayerIndex: 0, Place: 1}, {PlayerIndex: 1, Place: 2}}, history.Placings)
+	assert.Equal(game.deckCommitment.hash, history.DeckHash)
+	assert.Equal("6", history.Rules["handSize"])
+
+	assert.Len(history.Entries, 4)
+	defendEntry := history.Entries[2]
+	assert.Equal("Defend", defendEntry.Name)
+	assert.Equal("10♠", defendEntry.Params["defendingCard"])
+	assert.Equal([]*Card{{"7", "♥"}}, defendEntry.State.Battleground)
+	assert.Equal([]*Card{{"8", "♣"}}, defendEntry.State.PlayersCards[1])
+	assert.Equal([]*Card{{"6", "♠"}}, defendEntry.State.Deck)
+	assert.Equal(&Card{"6", "♠"}, defendEntry.State.Trump)
+
+	_, err = readGameHistory(dir, "../"+game.id)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestReadGameHistoryWithLifecycleEntries(t *testing.T) {
+	for _, format := range []string{GameLogFormatText, GameLogFormatJson} {
+		dir := newTestGameLogDir(t)
+		var logger BufferedGameLogger = newTestGameFileLogger(t, dir)
+		if format == GameLogFormatJson {
+			logger = NewGameJsonLogger(dir, func(err error) {
+				t.Errorf("Cannot write log: %s", err)
+			})
+		}
+		game := newTestGame(
+			[]*Card{{"7", "♥"}},
+			[]*Card{{"10", "♠"}},
+		)
+		game.players[0].Name = "Alice"
+		game.players[1].Name = "Bob"
+
+		logger.LogGameBegins(game)
+		logger.LogPlayerActionRejected(game, game.players[1], PlayerActionNameAttack, AttackActionData{Card: &Card{"10", "♠"}})
+		logger.LogRoundEnds(game, true)
+		carol := newPlayer(&TestClientSender{id: 3}, false)
+		carol.Name = "Carol"
+		game.players = append(game.players, carol)
+		logger.LogLatePlayerJoin(game, carol)
+		logger.LogPlayerLeft(game, 0, true)
+		logger.LogGameEnds(game, &GameEndEvent{Reason: GameEndReasonPlayerAfk, HasLoser: true, LoserIndex: 0})
+		game.status = GameStatusEnd
+		logger.LogGameDeleted(game)
+		logger.Wait()
+
+		history, err := readGameHistory(dir, game.id)
+		if err != nil {
+			t.Fatalf("Cannot read game: %s", err)
+		}
+		assert := assert.New(t)
+		names := make([]string, 0)
+		for _, entry := range history.Entries {
+			names = append(names, entry.Name)
+		}
+		assert.Equal([]string{
+			"Game begins",
+			"Action rejected",
+			"Round ends",
+			"Late player join",
+			"Player left",
+			"Game ends",
+			"Game deleted",
+		}, names, format)
+		assert.Equal("attack", history.Entries[1].Params["action"], format)
+		assert.Equal("true", history.Entries[2].Params["wasAttackSuccessful"], format)
+		assert.Equal("true", history.Entries[4].Params["isAfk"], format)
+		assert.Len(history.Players, 3, format)
+		assert.Equal("Carol", history.Players[2].Name, format)
+		assert.True(history.Players[2].IsLate, format)
+		assert.Equal("Alice", history.LoserName, format)
+		assert.True(history.IsEnded, format)
+		assert.True(history.IsDeleted, format)
+	}
+}
+
+func TestListGameHistory(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	game := writeTestGameLog(t, newTestGameFileLogger(t, dir))
+
+	withBots := false
+	cases := []struct {
+		filter   *GameHistoryFilter
+		expected int
+	}{
+		{&GameHistoryFilter{Limit: 10}, 1},
+		{&GameHistoryFilter{Player: "alice", Limit: 10}, 1},
+		{&GameHistoryFilter{Player: "Carol", Limit: 10}, 0},
+		{&GameHistoryFilter{Loser: "bot-Bob", Limit: 10}, 1},
+		{&GameHistoryFilter{Loser: "Alice", Limit: 10}, 0},
+		{&GameHistoryFilter{WithBots: &withBots, Limit: 10}, 0},
+		{&GameHistoryFilter{Month: game.id[:6], Limit: 10}, 1},
+		{&GameHistoryFilter{Month: "199001", Limit: 10}, 0},
+	}
+	for i, c := range cases {
+		summaries, err := listGameHistory(dir, c.filter)
+		assert.Nil(t, err)
+		assert.Len(t, summaries, c.expected, "case %d", i)
+	}
+}
+
+func TestApiGames(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	game := writeTestGameLog(t, newTestGameFileLogger(t, dir))
+
+	recorder := httptest.NewRecorder()
+	serveApiGames(dir, recorder, httptest.NewRequest("GET", "/api/games?player=Alice&limit=5", nil))
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, recorder.Code)
+	var summaries []*GameHistorySummary
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &summaries))
+	assert.Len(summaries, 1)
+	assert.Equal(game.id, summaries[0].Id)
+
+	recorder = httptest.NewRecorder()
+	serveApiGames(dir, recorder, httptest.NewRequest("GET", "/api/games/"+game.id, nil))
+	assert.Equal(http.StatusOK, recorder.Code)
+	var history GameHistory
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &history))
+	assert.Len(history.Entries, 4)
+
+	recorder = httptest.NewRecorder()
+	serveApiGames(dir, recorder, httptest.NewRequest("GET", "/api/games/20000101_000000_1", nil))
+	assert.Equal(http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	serveApiGames(dir, recorder, httptest.NewRequest("GET", "/api/games?withBots=maybe", nil))
+	assert.Equal(http.StatusBadRequest, recorder.Code)
+}
+
+func TestSuspendedGameIsNotServed(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	logger := newTestGameFileLogger(t, dir)
+	game := newTestGame(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"10", "♠"}},
+	)
+	logger.LogGameBegins(game)
+	logger.LogGameSuspended(game)
+	logger.Wait()
+
+	assert := assert.New(t)
+	_, err := readGameHistory(dir, game.id)
+	assert.True(os.IsNotExist(err))
+
+	recorder := httptest.NewRecorder()
+	serveApiGames(dir, recorder, httptest.NewRequest("GET", "/api/games/"+game.id, nil))
+	assert.Equal(http.StatusNotFound, recorder.Code)
+
+	summaries, err := listGameHistory(dir, &GameHistoryFilter{Limit: 10})
+	assert.Nil(err)
+	assert.Empty(summaries)
+
+	lobby, _, _ := newTestLobbyWithGame()
+	lobby.gameLogDir = dir
+	client := &Client{id: 3, lobby: lobby, isValid: true, send: make(chan []byte, 10)}
+	lobby.onOpenReplayCommand(client, game.id)
+	assert.Nil(client.replayRoom)
+	assert.Contains(string(<-client.send), errorGameIsNotFound)
+}
+
+func TestListGameHistoryFromIndex(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	logger := newTestGameFileLogger(t, dir)
+	game := writeTestGameLog(t, logger)
+	game.status = GameStatusEnd
+	logger.LogGameDeleted(game)
+	logger.Wait()
+
+	assert := assert.New(t)
+	logPath := filepath.Join(dir, game.id[:6], game.id+".log")
+	assert.Nil(os.Rename(logPath, logPath+".bak"))
+	summaries, err := listGameHistory(dir, &GameHistoryFilter{Limit: 10})
+	assert.Nil(err)
+	assert.Len(summaries, 1)
+	assert.True(summaries[0].IsDeleted, "the summary is updated after deleting of the ended game")
+
+	// Index is built from logs if there is no one
+	assert.Nil(os.Rename(logPath+".bak", logPath))
+	assert.Nil(os.Remove(filepath.Join(dir, game.id[:6], GameHistoryIndexFileName)))
+	summaries, err = listGameHistory(dir, &GameHistoryFilter{Limit: 10})
+	assert.Nil(err)
+	assert.Len(summaries, 1)
+	assert.Equal(game.id, summaries[0].Id)
+	assert.FileExists(filepath.Join(dir, game.id[:6], GameHistoryIndexFileName))
+}
+
+func TestReadGameHistoryWithEscapedNicknames(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	logger := newTestGameFileLogger(t, dir)
+	game := newTestGame(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"10", "♠"}},
+	)
+	game.players[0].Name = "Al;ice=1"
+	game.players[1].Name = "Bob\nENTRY Game ends."
+	logger.LogGameBegins(game)
+	carol := newPlayer(&TestClientSender{id: 3}, false)
+	carol.Name = "Carol%3B"
+	game.players = append(game.players, carol)
+	logger.LogLatePlayerJoin(game, carol)
+	logger.LogGameEnds(game, &GameEndEvent{Reason: GameEndReasonPlayerAfk, HasLoser: true, LoserIndex: 1})
+	logger.Wait()
+
+	history, err := readGameHistory(dir, game.id)
+	if err != nil {
+		t.Fatalf("Cannot read game: %s", err)
+	}
+	assert := assert.New(t)
+	assert.Len(history.Entries, 3)
+	assert.Equal("Al;ice=1", history.Players[0].Name)
+	assert.Equal("Bob\nENTRY Game ends.", history.LoserName)
+	assert.Equal("Carol%3B", history.Players[2].Name)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GameJsonLogVersion is version of the format of JSON-lines game logs
+const GameJsonLogVersion = 1
+
+// Formats of game logs
+const (
+	GameLogFormatText = "text"
+	GameLogFormatJson = "json"
+)
+
+// GameJsonLogger is implementation of GameLogger which stores logs in files as JSON-lines
+type GameJsonLogger struct {
+	*gameLogFiles
+}
+
+// GameJsonLogRecord is one line of JSON-lines game log
+type GameJsonLogRecord struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	GameId  string    `json:"gameId"`
+	Entry   string    `json:"entry"`
+	// Index of the player who made the action
+	ActorIndex *int `json:"actorIndex,omitempty"`
+	// Data of the action or the event
+	Action json.RawMessage `json:"action,omitempty"`
+	// Deck hash, rules and players are written when the game begins, seed is written when the game ends
+	Seed     *int64               `json:"seed,omitempty"`
+	DeckHash string               `json:"deckHash,omitempty"`
+	Rules    *GameRules           `json:"rules,omitempty"`
+	Players  []*GameHistoryPlayer `json:"players,omitempty"`
+	State    *GameHistoryState    `json:"state"`
+}
+
+// GameJsonLogRejectedAction contains the action which is not allowed by rules
+type GameJsonLogRejectedAction struct {
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+}
+
+// GameJsonLogRoundEnd contains outcome of the ended round
+type GameJsonLogRoundEnd struct {
+	WasAttackSuccessful bool `json:"wasAttackSuccessful"`
+}
+
+// NewGameJsonLogger constructor for GameJsonLogger
+func NewGameJsonLogger(dir string, errCallback func(err error)) *GameJsonLogger {
+	return &GameJsonLogger{newGameLogFiles(dir, ".jsonl", errCallback)}
+}
+
+// LogGameBegins starts recording log and adds record with deck hash, rules and players
+func (l *GameJsonLogger) LogGameBegins(game *Game) {
+	l.startWriteLoop(game.id)
+
+	record := l.newRecord(game, "Game begins", nil, nil)
+	record.DeckHash = game.deckCommitment.hash
+	record.Rules = game.rules
+	record.Players = make([]*GameHistoryPlayer, 0)
+	for index, player := range game.players {
+		record.Players = append(record.Players, &GameHistoryPlayer{
+			Index: index,
+			Name:  player.Name,
+			Team:  player.Team,
+			IsBot: player.isBot(),
+		})
+	}
+	l.addRecord(record)
+}
+
+// LogPlayerActionAttack adds record about attack
+func (l *GameJsonLogger) LogPlayerActionAttack(game *Game, player *Player, data AttackActionData) {
+	l.addRecord(l.newRecord(game, "Attack", player, data))
+}
+
+// LogPlayerActionDefend adds record about defense
+func (l *GameJsonLogger) LogPlayerActionDefend(game *Game, player *Player, data DefendActionData) {
+	l.addRecord(l.newRecord(game, "Defend", player, data))
+}
+
+// LogPlayerActionTransfer adds record about transfer
+func (l *GameJsonLogger) LogPlayerActionTransfer(game *Game, player *Player, data TransferActionData) {
+	l.addRecord(l.newRecord(game, "Transfer", player, data))
+}
+
+// LogPlayerActionPickUp adds record about pick up
+func (l *GameJsonLogger) LogPlayerActionPickUp(game *Game, player *Player) {
+	l.addRecord(l.newRecord(game, "PickUp", player, nil))
+}
+
+// LogPlayerActionComplete adds record about completing a round
+func (l *GameJsonLogger) LogPlayerActionComplete(game *Game, player *Player) {
+	l.addRecord(l.newRecord(game, "Complete", player, nil))
+}
+
+// LogPlayerActionRejected adds record about action which is not allowed by rules
+func (l *GameJsonLogger) LogPlayerActionRejected(game *Game, player *Player, actionName string, data interface{}) {
+	l.addRecord(l.newRecord(game, "Action rejected", player, &GameJsonLogRejectedAction{Name: actionName, Data: data}))
+}
+
+// LogRoundEnds adds record about ending a round with the state of the new round
+func (l *GameJsonLogger) LogRoundEnds(game *Game, wasAttackSuccessful bool) {
+	l.addRecord(l.newRecord(game, "Round ends", nil, &GameJsonLogRoundEnd{WasAttackSuccessful: wasAttackSuccessful}))
+}
+
+// LogPlayerLeft adds record about active player who left the game
+func (l *GameJsonLogger) LogPlayerLeft(game *Game, playerIndex int, isAfk bool) {
+	l.addRecord(l.newRecord(game, "Player left", game.players[playerIndex], &GamePlayerLeftEvent{PlayerIndex: playerIndex, IsAfk: isAfk}))
+}
+
+// LogLatePlayerJoin adds record about spectator who joined the running game
+func (l *GameJsonLogger) LogLatePlayerJoin(game *Game, player *Player) {
+	playerIndex := game.getPlayerIndex(player)
+	historyPlayer := &GameHistoryPlayer{Index: playerIndex, Name: player.Name, Team: player.Team, IsBot: player.isBot(), IsLate: true}
+	l.addRecord(l.newRecord(game, "Late player join", player, historyPlayer))
+}
+
+// LogGameDeleted adds record about deleting the game which is ended by its loop, the record is appended to the file
+func (l *GameJsonLogger) LogGameDeleted(game *Game) {
+	l.startWriteLoop(game.id)
+	l.addRecord(l.newRecord(game, "Game deleted", nil, nil))
+	l.stop(game.id)
+}
+
+// LogGameDraw adds record about draw
+func (l *GameJsonLogger) LogGameDraw(game *Game, data *GameDrawEvent) {
+	l.addRecord(l.newRecord(game, "Draw", nil, data))
+}
+
+// LogGameEnds adds record about ending game and writes file with records
+func (l *GameJsonLogger) LogGameEnds(game *Game, data *GameEndEvent) {
+	record := l.newRecord(game, "Game ends", nil, data)
+	seed := data.Seed
+	record.Seed = &seed
+	l.addRecord(record)
+	l.stop(game.id)
+}
+
+// LogGameSuspended adds record about saving the game to snapshot and writes file with records
+func (l *GameJsonLogger) LogGameSuspended(game *Game) {
+	l.addRecord(l.newRecord(game, "Game suspended", nil, nil))
+	l.stop(game.id)
+}
+
+// LogGameRestored continues recording log of the game which was suspended
+func (l *GameJsonLogger) LogGameRestored(game *Game) {
+	l.startWriteLoop(game.id)
+	l.addRecord(l.newRecord(game, "Game restored", nil, nil))
+}
+
+func (l *GameJsonLogger) newRecord(game *Game, entry string, player *Player, action interface{}) *GameJsonLogRecord {
+	record := &GameJsonLogRecord{
+		Version: GameJsonLogVersion,
+		Time:    time.Now(),
+		GameId:  game.id,
+		Entry:   entry,
+		State:   getGameHistoryState(game),
+	}
+	if player != nil {
+		actorIndex := game.getPlayerIndex(player)
+		record.ActorIndex = &actorIndex
+	}
+	if action != nil {
+		record.Action, _ = json.Marshal(action)
+	}
+	return record
+}
+
+func (l *GameJsonLogger) addRecord(record *GameJsonLogRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		l.errCallback(err)
+		return
+	}
+	l.add(record.GameId, string(line)+"\n")
+}
+
+// Returns the full state of the game
+func getGameHistoryState(game *Game) *GameHistoryState {
+	gameState := game.state
+	state := &GameHistoryState{
+		PlayersCards:    make([][]*Card, 0),
+		Deck:            gameState.deck.cards,
+		Battleground:    gameState.battleground,
+		DefendingCards:  make([]*Card, len(gameState.battleground)),
+		DiscardPileSize: gameState.discardPileSize,
+		AttackerIndex:   gameState.attackerIndex,
+		DefenderIndex:   gameState.defenderIndex,
+		Trump:           gameState.trumpCard,
+	}
+	for index := range game.players {
+		state.PlayersCards = append(state.PlayersCards, game.getPlayerState(index).cards)
+	}
+	for index, card := range gameState.defendingCards {
+		if index < len(state.DefendingCards) {
+			state.DefendingCards[index] = card
+		}
+	}
+	return state
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameJsonLogger(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	logger := NewGameJsonLogger(dir, func(err error) {
+		t.Errorf("Cannot write log: %s", err)
+	})
+	game := writeTestGameLog(t, logger)
+
+	path := filepath.Join(dir, game.id[:6], game.id+".jsonl")
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Cannot open log: %s", err)
+	}
+	defer f.Close()
+	records := make([]*GameJsonLogRecord, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record GameJsonLogRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Cannot parse line: %s", err)
+		}
+		records = append(records, &record)
+	}
+
+	assert := assert.New(t)
+	assert.Len(records, 4)
+	assert.Equal(GameJsonLogVersion, records[0].Version)
+	assert.Nil(records[0].Seed, "the seed is revealed when the game ends")
+	assert.Equal(game.seed, *records[3].Seed)
+	assert.Equal(newGameRules(), records[0].Rules)
+	assert.Equal(&GameHistoryPlayer{Index: 1, Name: "bot-Bob", IsBot: true}, records[0].Players[1])
+	assert.Nil(records[0].ActorIndex)
+	assert.False(records[0].Time.IsZero())
+
+	defendRecord := records[2]
+	assert.Equal("Defend", defendRecord.Entry)
+	assert.Equal(1, *defendRecord.ActorIndex)
+	var defendData DefendActionData
+	assert.Nil(json.Unmarshal(defendRecord.Action, &defendData))
+	assert.Equal(&Card{"10", "♠"}, defendData.DefendingCard)
+	assert.Equal([]*Card{{"10", "♠"}}, defendRecord.State.DefendingCards)
+
+	history, err := readGameHistory(dir, game.id)
+	assert.Nil(err)
+	assert.Equal("bot-Bob", history.LoserName)
+	assert.True(history.HasBots)
+	assert.Equal("6", history.Rules["handSize"])
+	assert.Len(history.Entries, 4)
+	assert.Equal(strconv.FormatInt(game.seed, 10), history.Seed)
+
+	f.Seek(0, 0)
+	assert.Nil(verifyGameJsonLog(f))
+}
+
+func TestDeletedRunningGameIsLoggedAfterItsEnd(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	logger := NewGameJsonLogger(dir, func(err error) {
+		t.Errorf("Cannot write log: %s", err)
+	})
+	lobby, room, clients := newTestLobbyWithGame()
+	game := room.game
+	game.gameLogger = logger
+	deckCommitment, err := newDeckCommitment(game.state.deck)
+	if err != nil {
+		t.Fatalf("Cannot create deck commitment: %s", err)
+	}
+	game.deckCommitment = deckCommitment
+	logger.LogGameBegins(game)
+	go game.loop()
+	go func() {
+		for range lobby.endedGames {
+		}
+	}()
+
+	room.onDeleteGameCommand(clients[0])
+	logger.Wait()
+
+	history, err := readGameHistory(dir, game.id)
+	if err != nil {
+		t.Fatalf("Cannot read game: %s", err)
+	}
+	names := make([]string, 0)
+	for _, entry := range history.Entries {
+		names = append(names, entry.Name)
+	}
+	assert := assert.New(t)
+	assert.Equal([]string{"Game begins", "Game ends", "Game deleted"}, names)
+	assert.True(history.IsDeleted)
+	assert.Equal(GameEndReasonDeleted, history.Reason)
+}
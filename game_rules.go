@@ -0,0 +1,82 @@
+package main
+
+import "fmt"
+
+// Who may throw in cards to the defender besides the attacker.
+const (
+	ThrowInAll        = "all"
+	ThrowInNeighbours = "neighbours"
+	ThrowInAttacker   = "attacker"
+)
+
+// Teams of players in team play
+const (
+	TeamNone   = 0
+	TeamFirst  = 1
+	TeamSecond = 2
+)
+
+// GameRules contains house rules of the game which are set by room owner before the game starts.
+type GameRules struct {
+	DeckSize              int    `json:"deckSize"`
+	HandSize              int    `json:"handSize"`
+	FirstRoundAttackLimit int    `json:"firstRoundAttackLimit"`
+	MaxCardsPerBout       int    `json:"maxCardsPerBout"`
+	ThrowIn               string `json:"throwIn"`
+	Transfer              bool   `json:"transfer"`
+	TeamPlay              bool   `json:"teamPlay"`
+	MoveSeconds           int    `json:"moveSeconds"`
+	TimeBankSeconds       int    `json:"timeBankSeconds"`
+}
+
+// Returns rules of the classic podkidnoy durak, transfers and the first round limit are opted in by room owner
+func newGameRules() *GameRules {
+	return &GameRules{
+		DeckSize:              DeckSize36,
+		HandSize:              6,
+		FirstRoundAttackLimit: 6,
+		MaxCardsPerBout:       6,
+		ThrowIn:               ThrowInAll,
+		Transfer:              false,
+		MoveSeconds:           DefaultMoveSeconds,
+		TimeBankSeconds:       0,
+	}
+}
+
+func (r *GameRules) validate() error {
+	if !isSupportedDeckSize(r.DeckSize) {
+		return fmt.Errorf("unsupported deck size: %d", r.DeckSize)
+	}
+	if r.HandSize < 1 {
+		return fmt.Errorf("hand size should be positive, got %d", r.HandSize)
+	}
+	if r.FirstRoundAttackLimit < 1 {
+		return fmt.Errorf("first round attack limit should be positive, got %d", r.FirstRoundAttackLimit)
+	}
+	if r.MaxCardsPerBout < 1 {
+		return fmt.Errorf("max cards per bout should be positive, got %d", r.MaxCardsPerBout)
+	}
+	if r.MoveSeconds < MinMoveSeconds {
+		return fmt.Errorf("move time should be at least %d seconds, got %d", MinMoveSeconds, r.MoveSeconds)
+	}
+	if r.TimeBankSeconds < 0 {
+		return fmt.Errorf("time bank should not be negative, got %d", r.TimeBankSeconds)
+	}
+	if r.getMaxPlayers() < 2 {
+		return fmt.Errorf("hand size %d is too big for deck of %d cards", r.HandSize, r.DeckSize)
+	}
+	if r.ThrowIn != ThrowInAll && r.ThrowIn != ThrowInNeighbours && r.ThrowIn != ThrowInAttacker {
+		return fmt.Errorf("unknown throw in rule: %s", r.ThrowIn)
+	}
+
+	return nil
+}
+
+// Returns maximum number of players who can get full hands from the deck
+func (r *GameRules) getMaxPlayers() int {
+	maxPlayers := r.DeckSize / r.HandSize
+	if maxPlayers > MaxPlayersInRoom {
+		return MaxPlayersInRoom
+	}
+	return maxPlayers
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestDefaultGameRulesAreValid(t *testing.T) {
+	rules := newGameRules()
+	if err := rules.validate(); err != nil {
+		t.Errorf("TestDefaultGameRulesAreValid got error: %s", err)
+	}
+}
+
+func TestDefaultGameRulesAreClassic(t *testing.T) {
+	rules := newGameRules()
+	if rules.Transfer {
+		t.Errorf("TestDefaultGameRulesAreClassic expected transfers to be off")
+	}
+	if rules.FirstRoundAttackLimit != rules.MaxCardsPerBout {
+		t.Errorf("TestDefaultGameRulesAreClassic expected no first round limit, got: %v", rules.FirstRoundAttackLimit)
+	}
+}
+
+func TestGameRulesUnknownThrowIn(t *testing.T) {
+	rules := newGameRules()
+	rules.ThrowIn = "nobody"
+	if err := rules.validate(); err == nil {
+		t.Errorf("TestGameRulesUnknownThrowIn must be error")
+	}
+}
+
+func TestGameRulesMaxPlayers(t *testing.T) {
+	rules := newGameRules()
+	expectedByDeckSize := map[int]int{DeckSize24: 4, DeckSize36: 6, DeckSize52: 8}
+	for deckSize, expected := range expectedByDeckSize {
+		rules.DeckSize = deckSize
+		got := rules.getMaxPlayers()
+		if got != expected {
+			t.Errorf("TestGameRulesMaxPlayers expected for deck %d: %v, got: %v", deckSize, expected, got)
+		}
+	}
+}
+
+func TestGameRulesTooBigHandSize(t *testing.T) {
+	rules := newGameRules()
+	rules.DeckSize = DeckSize24
+	rules.HandSize = 13
+	if err := rules.validate(); err == nil {
+		t.Errorf("TestGameRulesTooBigHandSize must be error")
+	}
+}
@@ -0,0 +1,933 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GameState contains cards, turns and results of the game by the rules. It knows nothing about clients,
+// channels, timers and logs: moves are listed by getLegalActions and made by apply, which returns the next state
+// and events for players. Game plays the state with connected clients, bots and tests play it in memory.
+type GameState struct {
+	rules                         *GameRules
+	players                       []*PlayerState
+	deck                          *Deck
+	discardPileSize               int
+	trumpSuit                     string
+	trumpCard                     *Card
+	trumpCardIsOwnedByPlayerIndex int
+	firstAttackerReasonCard       *Card
+	attackerIndex                 int
+	defenderIndex                 int
+	battleground                  []*Card
+	defendingCards                map[int]*Card
+	defenderPickUp                bool
+	roundsPlayed                  int
+	finishingOrder                [][]int
+	isEnded                       bool
+	endReason                     string
+	loserIndex                    int
+	loserTeam                     int
+}
+
+// PlayerState contains cards and status of a player in the game
+type PlayerState struct {
+	cards       []*Card
+	isActive    bool
+	isCompleted bool
+	team        int
+	stats       PlayerGameStats
+}
+
+// GameAction is a move of the player with given index. Data has type of the action, e.g. AttackActionData.
+type GameAction struct {
+	PlayerIndex int
+	Name        string
+	Data        interface{}
+}
+
+func newGameState(rules *GameRules, players []*Player) *GameState {
+	s := &GameState{
+		rules:          rules,
+		players:        make([]*PlayerState, 0),
+		deck:           &Deck{cards: make([]*Card, 0)},
+		battleground:   make([]*Card, 0),
+		defendingCards: make(map[int]*Card, 0),
+		finishingOrder: make([][]int, 0),
+		loserIndex:     -1,
+		loserTeam:      TeamNone,
+	}
+	for _, p := range players {
+		s.players = append(s.players, &PlayerState{cards: make([]*Card, 0), isActive: p.IsActive, team: p.Team})
+	}
+	return s
+}
+
+func newAttackAction(playerIndex int, card *Card) GameAction {
+	return GameAction{PlayerIndex: playerIndex, Name: PlayerActionNameAttack, Data: AttackActionData{Card: card}}
+}
+
+func newDefendAction(playerIndex int, attackingCard *Card, defendingCard *Card) GameAction {
+	data := DefendActionData{AttackingCard: attackingCard, DefendingCard: defendingCard}
+	return GameAction{PlayerIndex: playerIndex, Name: PlayerActionNameDefend, Data: data}
+}
+
+func newTransferAction(playerIndex int, card *Card) GameAction {
+	return GameAction{PlayerIndex: playerIndex, Name: PlayerActionNameTransfer, Data: TransferActionData{Card: card}}
+}
+
+func newPickUpAction(playerIndex int) GameAction {
+	return GameAction{PlayerIndex: playerIndex, Name: PlayerActionNamePickUp}
+}
+
+func newCompleteAction(playerIndex int) GameAction {
+	return GameAction{PlayerIndex: playerIndex, Name: PlayerActionNameComplete}
+}
+
+// Returns a copy which can be changed without changing this state. Cards are shared because they are not changed.
+func (s *GameState) clone() *GameState {
+	c := *s
+	c.players = make([]*PlayerState, len(s.players))
+	for i, p := range s.players {
+		player := *p
+		player.cards = append([]*Card{}, p.cards...)
+		c.players[i] = &player
+	}
+	c.deck = &Deck{cards: append([]*Card{}, s.deck.cards...)}
+	c.battleground = append([]*Card{}, s.battleground...)
+	c.defendingCards = make(map[int]*Card, len(s.defendingCards))
+	for index, card := range s.defendingCards {
+		c.defendingCards[index] = card
+	}
+	c.finishingOrder = append([][]int{}, s.finishingOrder...)
+	return &c
+}
+
+// Deals cards from the shuffled deck, the last card of the deck shows trump suit
+func (s *GameState) deal(deck *Deck) {
+	s.deck = deck
+	cardsLimit := s.rules.HandSize
+	var lastCard *Card
+	lastPlayerIndex := -1
+	for cardIndex := 0; cardIndex < cardsLimit; cardIndex = cardIndex + 1 {
+		for playerIndex, p := range s.players {
+			if !p.isActive || len(p.cards) >= cardsLimit {
+				break
+			}
+			card, err := s.deck.getCard()
+			if err == nil {
+				p.cards = append(p.cards, card)
+				lastCard = card
+				lastPlayerIndex = playerIndex
+			}
+		}
+	}
+	if len(s.deck.cards) > 0 {
+		lastCard = s.deck.cards[0]
+		s.trumpCardIsOwnedByPlayerIndex = -1
+	} else {
+		s.trumpCardIsOwnedByPlayerIndex = lastPlayerIndex
+	}
+
+	s.trumpCard = lastCard
+	if lastCard != nil {
+		s.trumpSuit = lastCard.Suit
+	}
+}
+
+// Chooses the first attacker: the leading player if it is given, e.g. the loser of the previous game in match,
+// or the player with the lowest trump
+func (s *GameState) chooseFirstAttacker(leadingPlayerIndex int) {
+	if leadingPlayerIndex >= 0 {
+		s.attackerIndex = leadingPlayerIndex
+		s.defenderIndex = s.getNextOpponentIndex(leadingPlayerIndex)
+		return
+	}
+	s.attackerIndex, s.defenderIndex, s.firstAttackerReasonCard = s.findFirstAttacker()
+}
+
+func (s *GameState) findFirstAttacker() (firstAttackerIndex int, defenderIndex int, lowestTrumpCard *Card) {
+	firstAttackerIndex = -1
+	defenderIndex = -1
+	lowestTrumpCard = &Card{"A", s.trumpSuit}
+
+	for playerIndex, p := range s.players {
+		for _, c := range p.cards {
+			if c.Suit == s.trumpSuit && c.lte(lowestTrumpCard) {
+				firstAttackerIndex = s.adjustPlayerIndex(playerIndex)
+				defenderIndex = s.getNextOpponentIndex(firstAttackerIndex)
+				lowestTrumpCard = c
+			}
+		}
+	}
+
+	if firstAttackerIndex >= 0 {
+		return
+	}
+
+	// fallback
+	for playerIndex, p := range s.players {
+		if !p.isActive {
+			break
+		}
+		for _, c := range p.cards {
+			if c.lte(lowestTrumpCard) {
+				firstAttackerIndex = playerIndex
+				defenderIndex = s.getNextOpponentIndex(firstAttackerIndex)
+				lowestTrumpCard = c
+			}
+		}
+	}
+
+	return
+}
+
+// Returns state of the game seen by the player with given index, negative index is for spectators.
+// Time of moves is not a part of the state and is left empty.
+func (s *GameState) getGameStateInfo(playerIndex int) *GameStateInfo {
+	gsi := &GameStateInfo{
+		YourHand:         make([]*Card, 0),
+		HandsSizes:       make([]int, len(s.players)),
+		DeckSize:         len(s.deck.cards),
+		DiscardPileSize:  s.discardPileSize,
+		TrumpCard:        s.trumpCard,
+		Battleground:     s.battleground,
+		DefendingCards:   s.defendingCards,
+		CompletedPlayers: make(map[int]bool, 0),
+		DefenderPickUp:   s.defenderPickUp,
+		AttackerIndex:    s.attackerIndex,
+		DefenderIndex:    s.defenderIndex,
+		TurnPlayerIndex:  s.getWaitingPlayerIndex(),
+		TimeBanksSeconds: make([]int, len(s.players)),
+	}
+
+	for i, p := range s.players {
+		if i == playerIndex {
+			gsi.YourHand = p.cards
+			gsi.CanYouComplete = s.canPlayerComplete(i)
+			gsi.CanYouAttack = s.canPlayerAttack(i)
+			gsi.CanYouPickUp = s.canPlayerPickUp(i)
+			gsi.CanYouTransfer = s.canPlayerTransfer(i)
+		}
+		gsi.HandsSizes[i] = len(p.cards)
+		gsi.CompletedPlayers[i] = p.isCompleted
+	}
+
+	return gsi
+}
+
+// Returns moves which the player with given index can make in this state
+func (s *GameState) getLegalActions(playerIndex int) []GameAction {
+	actions := make([]GameAction, 0)
+	if !s.isPlayerIndexValid(playerIndex) {
+		return actions
+	}
+	cards := s.players[playerIndex].cards
+
+	for _, card := range cards {
+		if s.canPlayerAttackWithCard(playerIndex, card) {
+			actions = append(actions, newAttackAction(playerIndex, card))
+		}
+	}
+	for _, attackingCard := range s.battleground {
+		for _, card := range cards {
+			if s.canPlayerDefendWithCard(playerIndex, attackingCard, card) {
+				actions = append(actions, newDefendAction(playerIndex, attackingCard, card))
+			}
+		}
+	}
+	for _, card := range cards {
+		if s.canPlayerTransferWithCard(playerIndex, card) {
+			actions = append(actions, newTransferAction(playerIndex, card))
+		}
+	}
+	if s.canPlayerPickUp(playerIndex) {
+		actions = append(actions, newPickUpAction(playerIndex))
+	}
+	if s.canPlayerComplete(playerIndex) {
+		actions = append(actions, newCompleteAction(playerIndex))
+	}
+
+	return actions
+}
+
+// Returns the state after the move and events about it, this state is not changed.
+// Returns error when the move is not allowed by rules.
+func (s *GameState) apply(action GameAction) (*GameState, []interface{}, error) {
+	next := s.clone()
+	events, err := next.play(action)
+	if err != nil {
+		return s, nil, err
+	}
+	return next, events, nil
+}
+
+// Returns the state after the active player left the game and events about it.
+// The game ends when the player leaves one opponent alone or the team of the player forfeits it.
+func (s *GameState) leave(playerIndex int, isAfk bool) (*GameState, []interface{}) {
+	next := s.clone()
+	events := []interface{}{&GamePlayerLeftEvent{PlayerIndex: playerIndex, IsAfk: isAfk}}
+
+	reason := GameEndReasonPlayerLeft
+	if isAfk {
+		reason = GameEndReasonPlayerAfk
+	}
+	if next.rules.TeamPlay {
+		// The team of the player who left forfeits the game
+		events = append(events, next.endGame(reason, -1, next.players[playerIndex].team))
+	} else if next.getActivePlayersNum() == 2 {
+		events = append(events, next.endGame(reason, playerIndex, TeamNone))
+	}
+
+	return next, events
+}
+
+// Returns the state of the game which ended without loser for given reason, e.g. when it was deleted
+func (s *GameState) end(reason string) (*GameState, []interface{}) {
+	next := s.clone()
+	return next, []interface{}{next.endGame(reason, -1, TeamNone)}
+}
+
+// Makes the move in this state
+func (s *GameState) play(action GameAction) ([]interface{}, error) {
+	playerIndex := action.PlayerIndex
+	if !s.isPlayerIndexValid(playerIndex) {
+		return nil, fmt.Errorf("player %d does not play the game", playerIndex)
+	}
+	notAllowedErr := fmt.Errorf("action %s of player %d is not allowed", action.Name, playerIndex)
+
+	switch action.Name {
+	case PlayerActionNameAttack:
+		data, ok := action.Data.(AttackActionData)
+		if !ok || data.Card == nil || !s.canPlayerAttackWithCard(playerIndex, data.Card) {
+			return nil, notAllowedErr
+		}
+		return s.attack(playerIndex, data.Card), nil
+	case PlayerActionNameDefend:
+		data, ok := action.Data.(DefendActionData)
+		if !ok || data.AttackingCard == nil || data.DefendingCard == nil ||
+			!s.canPlayerDefendWithCard(playerIndex, data.AttackingCard, data.DefendingCard) {
+			return nil, notAllowedErr
+		}
+		return s.defend(playerIndex, data.AttackingCard, data.DefendingCard), nil
+	case PlayerActionNameTransfer:
+		data, ok := action.Data.(TransferActionData)
+		if !ok || data.Card == nil || !s.canPlayerTransferWithCard(playerIndex, data.Card) {
+			return nil, notAllowedErr
+		}
+		return s.transfer(playerIndex, data.Card), nil
+	case PlayerActionNamePickUp:
+		if !s.canPlayerPickUp(playerIndex) {
+			return nil, notAllowedErr
+		}
+		s.defenderPickUp = true
+		return s.endMove(), nil
+	case PlayerActionNameComplete:
+		if !s.canPlayerComplete(playerIndex) {
+			return nil, notAllowedErr
+		}
+		s.players[playerIndex].isCompleted = true
+		return s.endMove(), nil
+	}
+
+	return nil, fmt.Errorf("unknown action %s of player %d", action.Name, playerIndex)
+}
+
+func (s *GameState) attack(playerIndex int, card *Card) []interface{} {
+	s.battleground = append(s.battleground, card)
+	s.removeCard(playerIndex, card)
+	s.countTrumpPlayed(playerIndex, card)
+
+	return []interface{}{GameAttackEvent{
+		AttackerIndex: playerIndex,
+		DefenderIndex: s.defenderIndex,
+		Card:          card,
+	}}
+}
+
+func (s *GameState) defend(playerIndex int, attackingCard *Card, defendingCard *Card) []interface{} {
+	attackingIndex := s.getBattlegroundCardIndex(attackingCard)
+	s.defendingCards[attackingIndex] = defendingCard
+	s.removeCard(playerIndex, defendingCard)
+	s.countTrumpPlayed(playerIndex, defendingCard)
+
+	// Allow other players to attack in the middle of a round
+	s.resetPlayersCompleteStatuses()
+
+	return []interface{}{GameDefendEvent{
+		DefenderIndex: playerIndex,
+		AttackingCard: attackingCard,
+		DefendingCard: defendingCard,
+	}}
+}
+
+func (s *GameState) transfer(playerIndex int, card *Card) []interface{} {
+	s.battleground = append(s.battleground, card)
+	s.removeCard(playerIndex, card)
+	s.countTrumpPlayed(playerIndex, card)
+
+	// Defender becomes the main attacker and the next player has to defend
+	s.attackerIndex = s.defenderIndex
+	s.defenderIndex = s.getTransferDefenderIndex()
+	s.resetPlayersCompleteStatuses()
+
+	return []interface{}{GameTransferEvent{
+		TransferrerIndex: s.attackerIndex,
+		DefenderIndex:    s.defenderIndex,
+		Card:             card,
+	}}
+}
+
+// Ends the round when everybody has completed it, otherwise players get the changed state
+func (s *GameState) endMove() []interface{} {
+	if s.areAllPlayersCompleted() {
+		return s.endRound()
+	}
+	return []interface{}{GameStateEvent{}}
+}
+
+// Deals cards, gives cards on the table to the defender who picked them up and checks if the game is over.
+// The new round is in events even if the game ends with it.
+func (s *GameState) endRound() []interface{} {
+	s.resetPlayersCompleteStatuses()
+	// Can change number of active players to end game
+	s.roundDeal(s.attackerIndex, s.defenderIndex)
+
+	defenderPlayer := s.players[s.defenderIndex]
+	wasAttackSuccessful := s.defenderPickUp
+	defenderPlayer.stats.RoundsDefended++
+	if s.defenderPickUp {
+		defenderPlayer.stats.PickUps++
+	} else {
+		defenderPlayer.stats.SuccessfulDefences++
+	}
+
+	if s.defenderPickUp {
+		for _, c := range s.battleground {
+			defenderPlayer.cards = append(defenderPlayer.cards, c)
+		}
+		// Defending cards are taken in order of the battleground, so the same moves always lead to the same state
+		for i := range s.battleground {
+			if c, ok := s.defendingCards[i]; ok {
+				defenderPlayer.cards = append(defenderPlayer.cards, c)
+			}
+		}
+	} else {
+		s.discardPileSize = s.discardPileSize + len(s.battleground) + len(s.defendingCards)
+	}
+
+	s.attackerIndex, s.defenderIndex = s.findNewAttacker(s.defenderPickUp)
+	s.battleground = make([]*Card, 0)
+	s.defendingCards = make(map[int]*Card, 0)
+	s.defenderPickUp = false
+	s.roundsPlayed++
+	events := []interface{}{NewRoundEvent{WasAttackSuccessful: wasAttackSuccessful}}
+
+	activePlayersIndexes := s.getActivePlayersIndexes()
+	activeTeams := s.getActiveTeams()
+
+	if len(activePlayersIndexes) == 0 {
+		// The last players got rid of cards in the same round, e.g. defender beat the last card with the last card
+		gameDrawEvent := &GameDrawEvent{PlayersIndexes: make([]int, 0)}
+		if len(s.finishingOrder) > 0 {
+			gameDrawEvent.PlayersIndexes = s.finishingOrder[len(s.finishingOrder)-1]
+		}
+		events = append(events, gameDrawEvent, s.endGame(GameEndReasonDraw, -1, TeamNone))
+	} else if s.rules.TeamPlay && len(activeTeams) == 1 {
+		// End of team game: the team whose members still have cards loses
+		events = append(events, s.endGame(GameEndReasonLoser, -1, activeTeams[0]))
+	} else if len(activePlayersIndexes) == 1 {
+		// End of game
+		events = append(events, s.endGame(GameEndReasonLoser, activePlayersIndexes[0], TeamNone))
+	}
+
+	return events
+}
+
+func (s *GameState) endGame(reason string, loserIndex int, loserTeam int) *GameEndEvent {
+	s.isEnded = true
+	s.endReason = reason
+	s.loserIndex = loserIndex
+	s.loserTeam = loserTeam
+
+	return &GameEndEvent{
+		Reason:     reason,
+		HasLoser:   loserIndex >= 0 || loserTeam != TeamNone,
+		LoserIndex: loserIndex,
+		LoserTeam:  loserTeam,
+		Placings:   s.getPlacings(),
+	}
+}
+
+func (s *GameState) dealToPlayer(playerIndex int) {
+	player := s.players[playerIndex]
+	if !player.isActive {
+		return
+	}
+	cardsLimit := s.rules.HandSize
+	for cardIndex := len(player.cards); cardIndex < cardsLimit; cardIndex = cardIndex + 1 {
+		if len(player.cards) >= cardsLimit {
+			break
+		}
+		card, err := s.deck.getCard()
+		if err == nil {
+			player.cards = append(player.cards, card)
+		}
+	}
+	if len(player.cards) == 0 {
+		player.isActive = false
+	}
+}
+
+func (s *GameState) roundDeal(firstIndex int, lastIndex int) {
+	activePlayersBeforeDeal := s.getActivePlayersIndexes()
+
+	s.dealToPlayer(firstIndex)
+	for index := range s.players {
+		if index != firstIndex && index != lastIndex {
+			s.dealToPlayer(index)
+		}
+	}
+	s.dealToPlayer(lastIndex)
+
+	s.recordFinishedPlayers(activePlayersBeforeDeal)
+}
+
+// Adds players who got rid of all cards in this round to finishing order. They share the same place.
+func (s *GameState) recordFinishedPlayers(activePlayersBeforeDeal []int) {
+	finishedPlayersIndexes := make([]int, 0)
+	for _, playerIndex := range activePlayersBeforeDeal {
+		if !s.players[playerIndex].isActive {
+			finishedPlayersIndexes = append(finishedPlayersIndexes, playerIndex)
+		}
+	}
+	if len(finishedPlayersIndexes) > 0 {
+		s.finishingOrder = append(s.finishingOrder, finishedPlayersIndexes)
+	}
+}
+
+func (s *GameState) findNewAttacker(wasPickUp bool) (attackerIndex int, defenderIndex int) {
+	// The defender attacks after the successful defence, the next player attacks after the pick up
+	attackerIndex = s.defenderIndex
+	if wasPickUp {
+		attackerIndex = s.defenderIndex + 1
+	}
+
+	attackerIndex = s.adjustPlayerIndex(attackerIndex)
+	// Players who have left the game or partners can sit between the attacker and the defender
+	defenderIndex = s.getNextOpponentIndex(attackerIndex)
+
+	return
+}
+
+func (s *GameState) canPlayerAttack(playerIndex int) bool {
+	if s.isEnded || !s.isPlayerIndexValid(playerIndex) {
+		return false
+	}
+	if s.defenderIndex == playerIndex {
+		return false
+	}
+	if s.arePartners(s.defenderIndex, playerIndex) {
+		return false
+	}
+	if len(s.battleground) == 0 && s.attackerIndex != playerIndex {
+		return false
+	}
+	if !s.canPlayerThrowIn(playerIndex) {
+		return false
+	}
+	if len(s.battleground) >= s.getBoutCardsLimit() {
+		return false
+	}
+	if len(s.battleground) >= len(s.players[s.defenderIndex].cards)+len(s.defendingCards) {
+		return false
+	}
+	if s.players[playerIndex].isCompleted {
+		return false
+	}
+
+	return true
+}
+
+// Checks if the player is allowed by rules to add cards to the attack of the main attacker
+func (s *GameState) canPlayerThrowIn(playerIndex int) bool {
+	if playerIndex == s.attackerIndex {
+		return true
+	}
+
+	switch s.rules.ThrowIn {
+	case ThrowInAttacker:
+		return false
+	case ThrowInNeighbours:
+		return playerIndex == s.getNextOpponentIndex(s.defenderIndex)
+	}
+
+	return true
+}
+
+// Returns maximum number of attacking cards in the current bout
+func (s *GameState) getBoutCardsLimit() int {
+	if s.roundsPlayed == 0 && s.rules.FirstRoundAttackLimit < s.rules.MaxCardsPerBout {
+		return s.rules.FirstRoundAttackLimit
+	}
+	return s.rules.MaxCardsPerBout
+}
+
+func (s *GameState) canPlayerAttackWithCard(playerIndex int, card *Card) bool {
+	if !s.canPlayerAttack(playerIndex) {
+		return false
+	}
+	if !s.players[playerIndex].hasCard(card) {
+		return false
+	}
+	if len(s.battleground) > 0 && (!s.hasBattlegroundSameValue(card) && !s.hasDefendingCardsSameValue(card)) {
+		return false
+	}
+
+	return true
+}
+
+func (s *GameState) canPlayerDefendWithCard(playerIndex int, attackingCard *Card, defendingCard *Card) bool {
+	if s.isEnded || !s.isPlayerIndexValid(playerIndex) {
+		return false
+	}
+	if !s.players[playerIndex].hasCard(defendingCard) {
+		return false
+	}
+	if s.defenderIndex != playerIndex || s.defenderPickUp {
+		return false
+	}
+	attackingIndex := s.getBattlegroundCardIndex(attackingCard)
+	if attackingIndex < 0 {
+		return false
+	}
+	if _, isBeaten := s.defendingCards[attackingIndex]; isBeaten {
+		return false
+	}
+	if s.trumpSuit != defendingCard.Suit && s.trumpSuit == attackingCard.Suit {
+		return false
+	}
+	if s.trumpSuit == defendingCard.Suit && s.trumpSuit != attackingCard.Suit {
+		return true
+	}
+	return defendingCard.gt(attackingCard)
+}
+
+func (s *GameState) canPlayerTransfer(playerIndex int) bool {
+	if s.isEnded || !s.isPlayerIndexValid(playerIndex) {
+		return false
+	}
+	if !s.players[playerIndex].isActive {
+		return false
+	}
+	if s.defenderIndex != playerIndex {
+		return false
+	}
+	if len(s.battleground) == 0 || len(s.defendingCards) > 0 {
+		return false
+	}
+	if s.defenderPickUp {
+		return false
+	}
+	if !s.rules.Transfer {
+		return false
+	}
+	if len(s.battleground) >= s.getBoutCardsLimit() {
+		return false
+	}
+	// Next defender should be able to cover all cards including the transferring one
+	nextDefenderIndex := s.getTransferDefenderIndex()
+	if nextDefenderIndex < 0 || len(s.players[nextDefenderIndex].cards) < len(s.battleground)+1 {
+		return false
+	}
+
+	return true
+}
+
+func (s *GameState) canPlayerTransferWithCard(playerIndex int, card *Card) bool {
+	if !s.canPlayerTransfer(playerIndex) {
+		return false
+	}
+	if !s.players[playerIndex].hasCard(card) {
+		return false
+	}
+	for _, c := range s.battleground {
+		if c.Value != card.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns index of the player who defends after the current defender transfers the attack
+func (s *GameState) getTransferDefenderIndex() int {
+	return s.getNextOpponentIndex(s.defenderIndex)
+}
+
+func (s *GameState) canPlayerPickUp(playerIndex int) bool {
+	if s.isEnded || !s.isPlayerIndexValid(playerIndex) {
+		return false
+	}
+	if !s.players[playerIndex].isActive {
+		return false
+	}
+	if s.defenderIndex != playerIndex {
+		return false
+	}
+
+	if len(s.battleground) == 0 {
+		return false
+	}
+	if s.defenderPickUp {
+		return false
+	}
+
+	return true
+}
+
+func (s *GameState) canPlayerComplete(playerIndex int) bool {
+	if s.isEnded || !s.isPlayerIndexValid(playerIndex) {
+		return false
+	}
+	player := s.players[playerIndex]
+	if !player.isActive {
+		return false
+	}
+	if s.defenderIndex == playerIndex &&
+		(len(s.battleground) != len(s.defendingCards) || !s.areAllAttackersCompleted()) {
+		return false
+	}
+	if s.attackerIndex == playerIndex && len(s.battleground) == 0 {
+		return false
+	}
+	if player.isCompleted {
+		return false
+	}
+
+	if len(s.battleground) != len(s.defendingCards) && !s.defenderPickUp {
+		return false
+	}
+
+	return true
+}
+
+// Returns index of the player who blocks the round: the attacker before the first card,
+// the defender with cards to beat, then attackers and the defender who have not completed the round
+func (s *GameState) getWaitingPlayerIndex() int {
+	if s.isEnded || s.attackerIndex < 0 || s.defenderIndex < 0 {
+		return -1
+	}
+	if len(s.battleground) == 0 {
+		return s.attackerIndex
+	}
+	if len(s.defendingCards) < len(s.battleground) && !s.defenderPickUp {
+		return s.defenderIndex
+	}
+	for i := 0; i < len(s.players); i++ {
+		playerIndex := (s.attackerIndex + i) % len(s.players)
+		p := s.players[playerIndex]
+		if playerIndex != s.defenderIndex && p.isActive && !p.isCompleted && s.canPlayerComplete(playerIndex) {
+			return playerIndex
+		}
+	}
+	if !s.defenderPickUp {
+		return s.defenderIndex
+	}
+	return -1
+}
+
+// Checks if the player with given index lost the ended game alone or with his team
+func (s *GameState) isLoser(playerIndex int) bool {
+	if s.loserIndex == playerIndex {
+		return true
+	}
+	return s.loserTeam != TeamNone && s.players[playerIndex].team == s.loserTeam
+}
+
+// Returns places of players by their indexes: players who were first to get rid of cards take first places.
+// Players who got rid of cards in the same round share the same place.
+// Players who still have cards share the place after them and the loser takes the last place.
+func (s *GameState) getPlaces() map[int]int {
+	places := make(map[int]int, 0)
+	finishedPlayersNum := 0
+	for _, playersIndexes := range s.finishingOrder {
+		for _, playerIndex := range playersIndexes {
+			places[playerIndex] = finishedPlayersNum + 1
+		}
+		finishedPlayersNum += len(playersIndexes)
+	}
+
+	activePlayersIndexes := s.getActivePlayersIndexes()
+	for _, playerIndex := range activePlayersIndexes {
+		if s.isLoser(playerIndex) {
+			places[playerIndex] = finishedPlayersNum + len(activePlayersIndexes)
+		} else {
+			places[playerIndex] = finishedPlayersNum + 1
+		}
+	}
+
+	return places
+}
+
+// Returns places of players ordered from the first place to the last one
+func (s *GameState) getPlacings() []*GamePlacing {
+	placings := make([]*GamePlacing, 0)
+	for playerIndex, place := range s.getPlaces() {
+		placings = append(placings, &GamePlacing{PlayerIndex: playerIndex, Place: place})
+	}
+	sort.Slice(placings, func(i, j int) bool {
+		if placings[i].Place == placings[j].Place {
+			return placings[i].PlayerIndex < placings[j].PlayerIndex
+		}
+		return placings[i].Place < placings[j].Place
+	})
+
+	return placings
+}
+
+func (s *GameState) resetPlayersCompleteStatuses() {
+	for _, p := range s.players {
+		p.isCompleted = false
+	}
+}
+
+func (s *GameState) removeCard(playerIndex int, card *Card) {
+	s.players[playerIndex].removeCard(card)
+}
+
+func (s *GameState) countTrumpPlayed(playerIndex int, card *Card) {
+	if card.Suit == s.trumpSuit {
+		s.players[playerIndex].stats.TrumpsPlayed++
+	}
+}
+
+func (s *GameState) isPlayerIndexValid(playerIndex int) bool {
+	return playerIndex >= 0 && playerIndex < len(s.players)
+}
+
+func (s *GameState) hasBattlegroundSameValue(card *Card) bool {
+	for _, c := range s.battleground {
+		if c.Value == card.Value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *GameState) hasDefendingCardsSameValue(card *Card) bool {
+	for _, c := range s.defendingCards {
+		if c.Value == card.Value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *GameState) areAllAttackersCompleted() bool {
+	for index, p := range s.players {
+		if p.isActive && index != s.defenderIndex && !p.isCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *GameState) areAllPlayersCompleted() bool {
+	for index, p := range s.players {
+		isDefenderAndPickUp := index == s.defenderIndex && s.defenderPickUp
+		if p.isActive && !p.isCompleted && !isDefenderAndPickUp {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *GameState) getActivePlayersIndexes() (playersIndexes []int) {
+	for index, p := range s.players {
+		if p.isActive {
+			playersIndexes = append(playersIndexes, index)
+		}
+	}
+	return
+}
+
+// Returns teams which still have members with cards
+func (s *GameState) getActiveTeams() (teams []int) {
+	teamsSet := make(map[int]bool, 0)
+	for _, p := range s.players {
+		if p.isActive && !teamsSet[p.team] {
+			teamsSet[p.team] = true
+			teams = append(teams, p.team)
+		}
+	}
+	return
+}
+
+// Checks if players with given indexes play in the same team
+func (s *GameState) arePartners(playerIndex int, otherPlayerIndex int) bool {
+	if !s.rules.TeamPlay || playerIndex < 0 || otherPlayerIndex < 0 || playerIndex == otherPlayerIndex {
+		return false
+	}
+	return s.players[playerIndex].team == s.players[otherPlayerIndex].team
+}
+
+// Returns index of the next active player after given index who is not a partner of that player
+func (s *GameState) getNextOpponentIndex(index int) int {
+	if index < 0 {
+		return -1
+	}
+	for i := 1; i < len(s.players); i++ {
+		nextIndex := (index + i) % len(s.players)
+		if s.players[nextIndex].isActive && !s.arePartners(index, nextIndex) {
+			return nextIndex
+		}
+	}
+	return -1
+}
+
+func (s *GameState) getActivePlayersNum() int {
+	return len(s.getActivePlayersIndexes())
+}
+
+func (s *GameState) adjustPlayerIndex(index int) int {
+	activePlayersNum := s.getActivePlayersNum()
+	if activePlayersNum < 2 {
+		return -1
+	}
+
+	index = index % len(s.players)
+	if !s.players[index].isActive {
+		return s.adjustPlayerIndex(index + 1)
+	}
+
+	return index
+}
+
+func (s *GameState) getBattlegroundCardIndex(card *Card) int {
+	for index, c := range s.battleground {
+		if c.equals(card) {
+			return index
+		}
+	}
+	return -1
+}
+
+func (p *PlayerState) removeCard(card *Card) {
+	cards := make([]*Card, 0)
+	for _, c := range p.cards {
+		if !c.equals(card) {
+			cards = append(cards, c)
+		}
+	}
+	p.cards = cards
+}
+
+func (p *PlayerState) hasCard(card *Card) bool {
+	for _, c := range p.cards {
+		if c.equals(card) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGameState(hands ...[]*Card) *GameState {
+	state := newGameState(newGameRules(), make([]*Player, 0))
+	for _, hand := range hands {
+		state.players = append(state.players, &PlayerState{cards: hand, isActive: true})
+	}
+	state.trumpCard = &Card{"6", "♠"}
+	state.trumpSuit = state.trumpCard.Suit
+	state.attackerIndex = 0
+	state.defenderIndex = 1
+
+	return state
+}
+
+func newTestTeamGameState(hands ...[]*Card) *GameState {
+	state := newTestGameState(hands...)
+	state.rules.TeamPlay = true
+	for i, p := range state.players {
+		p.team = TeamFirst + i%2
+	}
+	return state
+}
+
+func newTestTransferGameState(hands ...[]*Card) *GameState {
+	state := newTestGameState(hands...)
+	state.rules.Transfer = true
+	return state
+}
+
+func playTestActions(t *testing.T, state *GameState, actions ...GameAction) {
+	for _, action := range actions {
+		if _, err := state.play(action); err != nil {
+			t.Fatalf("Cannot play: %s", err)
+		}
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	state := newTestTransferGameState(
+		[]*Card{{"7", "♥"}, {"8", "♥"}},
+		[]*Card{{"7", "♦"}, {"9", "♦"}},
+		[]*Card{{"10", "♣"}, {"J", "♣"}, {"Q", "♣"}},
+	)
+	playTestActions(t, state, newAttackAction(0, &Card{"7", "♥"}))
+
+	assert := assert.New(t)
+	assert.True(state.canPlayerTransfer(1))
+	assert.False(state.canPlayerTransferWithCard(1, &Card{"9", "♦"}))
+
+	playTestActions(t, state, newTransferAction(1, &Card{"7", "♦"}))
+
+	assert.Equal(2, len(state.battleground))
+	assert.Equal(1, state.attackerIndex)
+	assert.Equal(2, state.defenderIndex)
+	assert.False(state.players[1].hasCard(&Card{"7", "♦"}))
+}
+
+func TestTransferNotEnoughCardsForNextDefender(t *testing.T) {
+	state := newTestTransferGameState(
+		[]*Card{{"7", "♥"}, {"8", "♥"}},
+		[]*Card{{"7", "♦"}, {"9", "♦"}},
+		[]*Card{{"10", "♣"}},
+	)
+	playTestActions(t, state, newAttackAction(0, &Card{"7", "♥"}))
+
+	assert.False(t, state.canPlayerTransfer(1))
+}
+
+func TestTransferAfterDefend(t *testing.T) {
+	state := newTestTransferGameState(
+		[]*Card{{"7", "♥"}, {"7", "♣"}},
+		[]*Card{{"7", "♦"}, {"9", "♥"}},
+		[]*Card{{"10", "♣"}, {"J", "♣"}, {"Q", "♣"}},
+	)
+	playTestActions(t, state,
+		newAttackAction(0, &Card{"7", "♥"}),
+		newDefendAction(1, &Card{"7", "♥"}, &Card{"9", "♥"}),
+		newAttackAction(0, &Card{"7", "♣"}),
+	)
+
+	assert.False(t, state.canPlayerTransfer(1))
+}
+
+func TestTransferBackToAttackerOfTwoPlayers(t *testing.T) {
+	state := newTestTransferGameState(
+		[]*Card{{"7", "♥"}, {"8", "♥"}, {"9", "♣"}},
+		[]*Card{{"7", "♦"}, {"9", "♦"}},
+	)
+	playTestActions(t, state,
+		newAttackAction(0, &Card{"7", "♥"}),
+		newTransferAction(1, &Card{"7", "♦"}),
+	)
+
+	assert := assert.New(t)
+	assert.Equal(1, state.attackerIndex)
+	assert.Equal(0, state.defenderIndex)
+}
+
+func TestThrowInByNeighboursOnly(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}, {"8", "♥"}},
+		[]*Card{{"9", "♦"}, {"10", "♦"}},
+		[]*Card{{"7", "♣"}, {"J", "♣"}},
+		[]*Card{{"7", "♦"}, {"Q", "♣"}},
+	)
+	state.rules.ThrowIn = ThrowInNeighbours
+	playTestActions(t, state, newAttackAction(0, &Card{"7", "♥"}))
+
+	assert := assert.New(t)
+	assert.True(state.canPlayerAttackWithCard(2, &Card{"7", "♣"}))
+	assert.False(state.canPlayerAttackWithCard(3, &Card{"7", "♦"}))
+}
+
+func TestThrowInByAttackerOnly(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}, {"7", "♠"}},
+		[]*Card{{"9", "♦"}, {"10", "♦"}},
+		[]*Card{{"7", "♣"}, {"J", "♣"}},
+	)
+	state.rules.ThrowIn = ThrowInAttacker
+	playTestActions(t, state, newAttackAction(0, &Card{"7", "♥"}))
+
+	assert := assert.New(t)
+	assert.True(state.canPlayerAttackWithCard(0, &Card{"7", "♠"}))
+	assert.False(state.canPlayerAttackWithCard(2, &Card{"7", "♣"}))
+}
+
+func TestFirstRoundAttackLimit(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}, {"7", "♠"}, {"7", "♣"}},
+		[]*Card{{"9", "♦"}, {"10", "♦"}, {"J", "♦"}, {"Q", "♦"}},
+	)
+	state.rules.FirstRoundAttackLimit = 2
+	playTestActions(t, state,
+		newAttackAction(0, &Card{"7", "♥"}),
+		newAttackAction(0, &Card{"7", "♠"}),
+	)
+
+	assert := assert.New(t)
+	assert.False(state.canPlayerAttack(0))
+
+	state.roundsPlayed = 1
+	assert.True(state.canPlayerAttack(0))
+}
+
+func TestPartnerCannotThrowIn(t *testing.T) {
+	state := newTestTeamGameState(
+		[]*Card{{"7", "♥"}, {"8", "♥"}},
+		[]*Card{{"9", "♦"}, {"10", "♦"}},
+		[]*Card{{"7", "♣"}, {"J", "♣"}},
+		[]*Card{{"7", "♦"}, {"Q", "♣"}},
+	)
+	playTestActions(t, state, newAttackAction(0, &Card{"7", "♥"}))
+
+	assert := assert.New(t)
+	assert.True(state.canPlayerAttackWithCard(2, &Card{"7", "♣"}))
+	assert.False(state.canPlayerAttackWithCard(3, &Card{"7", "♦"}))
+}
+
+func TestNewAttackerSkipsPartner(t *testing.T) {
+	state := newTestTeamGameState(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"9", "♦"}},
+		[]*Card{{"7", "♣"}},
+		[]*Card{{"7", "♦"}},
+	)
+	state.players[2].isActive = false
+	state.attackerIndex = 1
+	state.defenderIndex = 3
+
+	attackerIndex, defenderIndex := state.findNewAttacker(false)
+
+	assert := assert.New(t)
+	assert.Equal(3, attackerIndex)
+	assert.Equal(0, defenderIndex)
+
+	state.attackerIndex = 3
+	state.defenderIndex = 0
+	attackerIndex, defenderIndex = state.findNewAttacker(false)
+	assert.Equal(0, attackerIndex)
+	assert.Equal(1, defenderIndex)
+}
+
+func TestNextPlayerAttacksAfterPickUp(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"9", "♦"}},
+		[]*Card{},
+		[]*Card{{"7", "♦"}},
+	)
+	state.players[2].isActive = false
+	state.attackerIndex = 1
+	state.defenderIndex = 3
+
+	attackerIndex, defenderIndex := state.findNewAttacker(true)
+
+	assert := assert.New(t)
+	assert.Equal(0, attackerIndex)
+	assert.Equal(1, defenderIndex)
+
+	attackerIndex, defenderIndex = state.findNewAttacker(false)
+	assert.Equal(3, attackerIndex)
+	assert.Equal(0, defenderIndex)
+}
+
+func TestTeamWithCardsLoses(t *testing.T) {
+	state := newTestTeamGameState(
+		[]*Card{{"7", "♥"}},
+		[]*Card{},
+		[]*Card{{"7", "♣"}},
+		[]*Card{},
+	)
+	state.players[1].isActive = false
+	state.players[3].isActive = false
+
+	assert.Equal(t, []int{TeamFirst}, state.getActiveTeams())
+}
+
+func TestPlayersFinishedInSameRoundSharePlace(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{},
+		[]*Card{},
+		[]*Card{{"7", "♣"}},
+		[]*Card{{"8", "♣"}},
+	)
+	state.roundDeal(0, 1)
+	state.loserIndex = 3
+
+	expected := []*GamePlacing{
+		{PlayerIndex: 0, Place: 1},
+		{PlayerIndex: 1, Place: 1},
+		{PlayerIndex: 2, Place: 3},
+		{PlayerIndex: 3, Place: 4},
+	}
+	assert.Equal(t, expected, state.getPlacings())
+}
+
+func TestPickUpKeepsAttacker(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}, {"8", "♣"}},
+		[]*Card{{"6", "♦"}, {"9", "♦"}},
+	)
+	state.deck = &Deck{cards: []*Card{{"6", "♠"}, {"10", "♣"}}}
+
+	playTestActions(t, state, newAttackAction(0, &Card{"7", "♥"}))
+	assert := assert.New(t)
+	assert.Equal([]GameAction{newPickUpAction(1)}, state.getLegalActions(1))
+
+	playTestActions(t, state, newPickUpAction(1))
+	assert.Equal(0, state.getWaitingPlayerIndex())
+	assert.Equal([]GameAction{newCompleteAction(0)}, state.getLegalActions(0))
+
+	events, err := state.play(newCompleteAction(0))
+	assert.Nil(err)
+	assert.Equal([]interface{}{NewRoundEvent{WasAttackSuccessful: true}}, events)
+	assert.Equal([]*Card{{"8", "♣"}, {"10", "♣"}, {"6", "♠"}}, state.players[0].cards)
+	assert.Equal([]*Card{{"6", "♦"}, {"9", "♦"}, {"7", "♥"}}, state.players[1].cards)
+	assert.Empty(state.deck.cards)
+	assert.Equal(0, state.attackerIndex)
+	assert.Equal(1, state.defenderIndex)
+	assert.Empty(state.battleground)
+	assert.False(state.isEnded)
+}
+
+func TestApplyDoesNotChangeState(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}, {"8", "♣"}},
+		[]*Card{{"6", "♦"}, {"9", "♦"}},
+	)
+
+	next, events, err := state.apply(newAttackAction(0, &Card{"7", "♥"}))
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Len(events, 1)
+	assert.IsType(GameAttackEvent{}, events[0])
+	assert.Equal([]*Card{{"7", "♥"}}, next.battleground)
+	assert.Empty(state.battleground)
+	assert.Len(state.players[0].cards, 2)
+
+	_, _, err = state.apply(newDefendAction(1, &Card{"7", "♥"}, &Card{"9", "♦"}))
+	assert.NotNil(err)
+}
+
+func TestEndsWithLoser(t *testing.T) {
+	state := newTestGameState(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"8", "♥"}, {"9", "♦"}},
+	)
+	state.roundsPlayed = 1
+
+	playTestActions(t, state,
+		newAttackAction(0, &Card{"7", "♥"}),
+		newDefendAction(1, &Card{"7", "♥"}, &Card{"8", "♥"}),
+		newCompleteAction(0),
+		newCompleteAction(1),
+	)
+
+	assert := assert.New(t)
+	assert.True(state.isEnded)
+	assert.Equal(GameEndReasonLoser, state.endReason)
+	assert.Equal(1, state.loserIndex)
+	assert.False(state.isLoser(0))
+	assert.True(state.isLoser(1))
+	assert.Equal(-1, state.getWaitingPlayerIndex())
+}
+
+// Plays random legal moves in dealt games: every move of legal actions must be accepted,
+// cards must not appear or disappear and the game must not get stuck.
+func TestRandomGamesFollowRules(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	assert := assert.New(t)
+	for i := 0; i < 200; i++ {
+		rules := newGameRules()
+		rules.Transfer = i%2 == 1
+		playersNum := 2 + i%5
+		if i%3 == 0 {
+			rules.DeckSize = DeckSize52
+		}
+		players := make([]*Player, playersNum)
+		for j := range players {
+			players[j] = &Player{IsActive: true}
+		}
+		if playersNum == 4 && i%4 == 0 {
+			rules.TeamPlay = true
+			for j, p := range players {
+				p.Team = TeamFirst + j%2
+			}
+		}
+
+		deck := newDeck(rules.DeckSize)
+		deck.shuffle(rng)
+		state := newGameState(rules, players)
+		state.deal(deck)
+		state.chooseFirstAttacker(-1)
+
+		for moves := 0; !state.isEnded; moves++ {
+			if !assert.Less(moves, 2000, "game %d is too long", i) {
+				return
+			}
+			playerIndex := state.getWaitingPlayerIndex()
+			actions := state.getLegalActions(playerIndex)
+			if !assert.NotEmpty(actions, "game %d: player %d has no moves", i, playerIndex) {
+				return
+			}
+			next, _, err := state.apply(actions[rng.Intn(len(actions))])
+			if !assert.Nil(err, "game %d", i) {
+				return
+			}
+			state = next
+
+			cardsNum := len(state.deck.cards) + len(state.battleground) + len(state.defendingCards) + state.discardPileSize
+			for _, p := range state.players {
+				cardsNum += len(p.cards)
+			}
+			if !assert.Equal(rules.DeckSize, cardsNum, "game %d", i) {
+				return
+			}
+		}
+	}
+}
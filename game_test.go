@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestClientSender struct {
+	id       uint64
+	nickname string
+}
+
+func (c *TestClientSender) sendEvent(event interface{}) {}
+
+func (c *TestClientSender) sendMessage(message []byte) {}
+
+func (c *TestClientSender) Id() uint64 {
+	return c.id
+}
+
+func (c *TestClientSender) Nickname() string {
+	return c.nickname
+}
+
+func (c *TestClientSender) AccountId() uint64 {
+	return 0
+}
+
+func (c *TestClientSender) Rating() int {
+	return 0
+}
+
+type TestGameLogger struct{}
+
+func (l *TestGameLogger) LogGameBegins(game *Game)                                                {}
+func (l *TestGameLogger) LogPlayerActionAttack(game *Game, player *Player, data AttackActionData) {}
+func (l *TestGameLogger) LogPlayerActionDefend(game *Game, player *Player, data DefendActionData) {}
+func (l *TestGameLogger) LogPlayerActionTransfer(game *Game, player *Player, data TransferActionData) {
+}
+func (l *TestGameLogger) LogPlayerActionPickUp(game *Game, player *Player)   {}
+func (l *TestGameLogger) LogPlayerActionComplete(game *Game, player *Player) {}
+func (l *TestGameLogger) LogPlayerActionRejected(game *Game, player *Player, actionName string, data interface{}) {
+}
+func (l *TestGameLogger) LogRoundEnds(game *Game, wasAttackSuccessful bool)     {}
+func (l *TestGameLogger) LogPlayerLeft(game *Game, playerIndex int, isAfk bool) {}
+func (l *TestGameLogger) LogLatePlayerJoin(game *Game, player *Player)          {}
+func (l *TestGameLogger) LogGameDeleted(game *Game)                             {}
+func (l *TestGameLogger) LogGameDraw(game *Game, data *GameDrawEvent)           {}
+func (l *TestGameLogger) LogGameEnds(game *Game, data *GameEndEvent)            {}
+func (l *TestGameLogger) LogGameSuspended(game *Game)                           {}
+func (l *TestGameLogger) LogGameRestored(game *Game)                            {}
+
+func newTestRoom() *Room {
+	lobby := newLobby(&TestGameLogger{}, &AccountFileStore{accounts: make([]*Account, 0)}, &RatingFileStore{ratings: make(map[uint64]*PlayerRating, 0)}, &StatsFileStore{stats: make(map[uint64]*PlayerStats, 0)})
+	go func() {
+		for range lobby.broadcast {
+		}
+	}()
+	return newRoom(1, &Client{id: 100}, lobby)
+}
+
+func newTestGame(hands ...[]*Card) *Game {
+	players := make([]*Player, 0)
+	for i := range hands {
+		players = append(players, newPlayer(&TestClientSender{id: uint64(i + 1)}, true))
+	}
+	game := newGame(newTestRoom(), players, &TestGameLogger{}, newGameRules())
+	game.state = newTestGameState(hands...)
+	game.rules = game.state.rules
+	game.status = GameStatusPlaying
+
+	return game
+}
+
+func TestDrawWhenDefenderBeatsLastCardWithLastCard(t *testing.T) {
+	game := newTestGame(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"9", "♥"}},
+	)
+	game.applyAction(newAttackAction(0, &Card{"7", "♥"}))
+	game.applyAction(newDefendAction(1, &Card{"7", "♥"}, &Card{"9", "♥"}))
+	game.applyAction(newCompleteAction(0))
+	game.applyAction(newCompleteAction(1))
+
+	assert := assert.New(t)
+	assert.Equal(GameStatusEnd, game.status)
+	assert.Equal(GameEndReasonDraw, game.state.endReason)
+	assert.Equal(-1, game.state.loserIndex)
+}
+
+func TestLoserWhenOnePlayerHasCards(t *testing.T) {
+	game := newTestGame(
+		[]*Card{{"7", "♥"}},
+		[]*Card{{"9", "♥"}, {"10", "♥"}},
+	)
+	game.applyAction(newAttackAction(0, &Card{"7", "♥"}))
+	game.applyAction(newDefendAction(1, &Card{"7", "♥"}, &Card{"9", "♥"}))
+	game.applyAction(newCompleteAction(0))
+	game.applyAction(newCompleteAction(1))
+
+	assert := assert.New(t)
+	assert.Equal(GameEndReasonLoser, game.state.endReason)
+	assert.Equal(1, game.state.loserIndex)
+}
+
+func TestSameSeedDealsSameCards(t *testing.T) {
+	game1 := newTestGame([]*Card{}, []*Card{})
+	game1.setSeed(42)
+	game1.prepare()
+	game2 := newTestGame([]*Card{}, []*Card{})
+	game2.setSeed(42)
+	game2.prepare()
+
+	assert := assert.New(t)
+	assert.Equal(game1.state.deck.asString(), game2.state.deck.asString())
+	assert.Equal(game1.state.players[0].cards, game2.state.players[0].cards)
+	assert.Equal(game1.state.trumpCard, game2.state.trumpCard)
+}
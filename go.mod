@@ -5,4 +5,5 @@ go 1.14
 require (
 	github.com/gorilla/websocket v1.4.2
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
 )
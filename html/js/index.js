@@ -74,6 +74,10 @@ Vue.component('opponent', {
         },
         isDefender: {
             type: Boolean
+        },
+        // Cards are shown in replays only
+        cards: {
+            type: Array
         }
     }
 });
@@ -110,6 +114,9 @@ function App() {
                 yourNickname: '',
                 clients: []
             },
+            weekLeaderboard: [],
+            botLevel: 'normal',
+            myLastGames: [],
             roomsInfo: {
                 rooms: [],
                 room: {},
@@ -132,6 +139,7 @@ function App() {
                 canYouPickUp: false,
                 canYouAttack: false,
                 canYouComplete: false,
+                canYouTransfer: false,
                 handsSizes: [],
                 deckSize: 0,
                 discardPileSize: 0,
@@ -141,7 +149,15 @@ function App() {
                 completedPlayers: {}, // {0: true, 1: false}
                 defenderPickUp: false,
                 attackerIndex: -1,
-                defenderIndex: -1
+                defenderIndex: -1,
+                hands: []
+            },
+            replay: {
+                gameId: '',
+                step: 0,
+                stepsNum: 0,
+                isPlaying: false,
+                speed: 1
             }
         },
         methods: {
@@ -157,6 +173,18 @@ function App() {
                 }
                 app.commandJoinRoom(roomId);
             },
+            openReplay: (gameId) => {
+                app.sendCommand('replay', 'open', gameId);
+            },
+            controlReplay: (controlData) => {
+                app.sendCommand('replay', 'control', controlData);
+            },
+            closeReplay: () => {
+                app.sendCommand('replay', 'close', null);
+            },
+            showMyLastGames: () => {
+                app.loadMyLastGames();
+            },
             markWantToPlay: () => {
                 app.commandWantToPlay();
                 app.vue.roomsInfo.wantToPlay = true;
@@ -175,7 +203,7 @@ function App() {
                 app.commandDeleteGame();
             },
             addBot: () => {
-                app.commandAddBot();
+                app.commandAddBot(app.vue.botLevel);
             },
             removeBots: () => {
                 app.commandRemoveBots();
@@ -206,6 +234,13 @@ function App() {
                 );
                 app.vue.game.pickedCard = null;
             },
+            transfer: () => {
+                if (!app.vue.gameStateInfo.canYouTransfer || !app.vue.game.pickedCard) {
+                    return;
+                }
+                app.commandTransfer(app.vue.game.pickedCard.value, app.vue.game.pickedCard.suit);
+                app.vue.game.pickedCard = null;
+            },
             pickUp: () => {
                 app.commandPickUp();
             },
@@ -275,6 +310,13 @@ function App() {
         app.vue.clientsInfo.yourNickname = data.yourNickname;
         app.vue.clientsInfo.clients = data.clients;
         app.vue.roomsInfo.rooms = data.rooms;
+        window.sessionStorage.setItem('sessionToken', data.sessionToken);
+
+        if (window.IS_RESUMING_SESSION) {
+            // Server sends the room and the game of the resumed session
+            window.IS_RESUMING_SESSION = false;
+            return;
+        }
 
         const roomIdFromHash = app.getFromHash('roomId');
 
@@ -298,6 +340,57 @@ function App() {
         app.vue.clientsInfo.clients.push(data);
     };
 
+    this.loadMyLastGames = () => {
+        const nickname = encodeURIComponent(app.vue.clientsInfo.yourNickname);
+        fetch('/api/games?limit=10&player=' + nickname)
+            .then((response) => response.json())
+            .then((games) => {
+                app.vue.myLastGames = games;
+            })
+            .catch((error) => console.warn("Cannot load games", error));
+    };
+
+    this.onReplayOpenedEvent = (data) => {
+        app.vue.replay.gameId = data.gameId;
+        app.vue.replay.stepsNum = data.stepsNum;
+        app.vue.game.gameEnd = false;
+        app.vue.roomsInfo.room = {gameStatus: 'playing'};
+    };
+
+    this.onReplayStateEvent = (data) => {
+        app.vue.replay.step = data.step;
+        app.vue.replay.stepsNum = data.stepsNum;
+        app.vue.replay.isPlaying = data.isPlaying;
+        app.vue.replay.speed = data.speed;
+        if (data.step < data.stepsNum - 1) {
+            app.vue.game.gameEnd = false;
+        }
+    };
+
+    this.onReplayClosedEvent = () => {
+        app.vue.replay.gameId = '';
+        app.vue.gameStateInfo.hands = [];
+        app.vue.roomsInfo.room = {};
+    };
+
+    this.onLeaderboardEvent = (data) => {
+        for (let ind = 0; ind < data.leaderboards.length; ind++) {
+            const leaderboard = data.leaderboards[ind];
+            if (leaderboard.period === 'week' && leaderboard.orderBy === 'rating') {
+                app.vue.weekLeaderboard = leaderboard.entries;
+            }
+        }
+    };
+
+    this.onClientRatingUpdatedEvent = (data) => {
+        const clients = app.vue.clientsInfo.clients;
+        for (let ind = 0; ind < clients.length; ind++) {
+            if (clients[ind].id === data.id) {
+                clients[ind].rating = data.rating;
+            }
+        }
+    };
+
     this.onClientLeftEvent = (data) => {
         let clients = app.vue.clientsInfo.clients;
         for (let ind = 0; ind < clients.length; ind++) {
@@ -339,7 +432,24 @@ function App() {
         app.updatePlayersInRoomCounter();
     };
 
+    this.onClientLoggedInEvent = (data) => {
+        const date = new Date;
+        date.setDate(date.getDate() + 180);
+        document.cookie = "token=" + data.token + "; path=/; expires=" + date.toUTCString();
+        document.cookie = "tokenNickname=" + data.nickname + "; path=/; expires=" + date.toUTCString();
+    };
+
     this.onClientCommandError = (data) => {
+        if (!app.vue.clientsInfo.yourId) {
+            // Client has not joined the lobby because of wrong credentials or expired session
+            window.sessionStorage.removeItem('sessionToken');
+            if (!window.IS_RESUMING_SESSION) {
+                document.cookie = "token=; path=/; expires=" + (new Date(0)).toUTCString();
+                window.alert(app.vue.$t('errors.' + data.message));
+            }
+            window.WsConnection.close();
+            return;
+        }
         app.vue.commandError = data;
         console.error(data.message);
         window.setTimeout(() => {
@@ -405,6 +515,10 @@ function App() {
         app.updateGameStateInfo(data.gameStateInfo);
     };
 
+    this.onGameTransferEvent = (data) => {
+        app.updateGameStateInfo(data.gameStateInfo);
+    };
+
     this.onGameStateEvent = (data) => {
         app.updateGameStateInfo(data.gameStateInfo);
         app.vue.game.firstAttackerReasonCard = null;
@@ -473,8 +587,8 @@ function App() {
         app.sendCommand('room', 'deleteGame', null);
     };
 
-    this.commandAddBot = () => {
-        app.sendCommand('room', 'addBot', null);
+    this.commandAddBot = (level) => {
+        app.sendCommand('room', 'addBot', {level});
     };
 
     this.commandRemoveBots = () => {
@@ -491,6 +605,10 @@ function App() {
         app.sendCommand('game', 'defend', { attackingCard, defendingCard });
     };
 
+    this.commandTransfer = (value, suit) => {
+        app.sendCommand('game', 'transfer', {card: {value, suit}});
+    };
+
     this.commandPickUp = () => {
         app.sendCommand('game', 'pickUp');
     };
@@ -5,6 +5,8 @@ window.i18nMessages = {
             an_online_multiplayer_card_game: 'An online multiplayer card game',
             nickname: 'nickname',
             play: 'Play',
+            password: 'password',
+            register: 'Register',
             websockets_are_not_supported: 'Sorry, you can\'t play because you device does not support WebSockets',
             disconnected: 'Disconnected',
             durak_an_online_multiplayer_card_game: 'Durak - an online multiplayer card game'
@@ -15,6 +17,14 @@ window.i18nMessages = {
             your_nickname: 'Your nickname',
             your_id: 'Your ID',
             rooms: 'Rooms',
+            week_leaderboard: 'Leaders of the week',
+            my_last_games: 'My last games',
+            lost: 'lost',
+            replay: 'Replay',
+            replay_play: 'Play',
+            replay_pause: 'Pause',
+            replay_speed: 'Speed',
+            replay_close: 'Close replay',
             room: 'Room',
             here: 'You are here',
             your_are_owner: 'you are owner',
@@ -32,10 +42,18 @@ window.i18nMessages = {
             complete_game: 'Complete the game',
             game: 'Game',
             add_bot: 'Add a bot',
+            bot_levels: {
+                easy: 'easy',
+                normal: 'normal',
+                hard: 'hard',
+                expert: 'expert',
+                trained: 'trained on games',
+            },
             remove_bots: 'Remove all bots',
         },
         game: {
             pick_up: 'Pick up',
+            transfer: 'Transfer',
             complete: 'Complete',
             is_looser: 'is loser',
             draw: 'draw',
@@ -59,6 +77,23 @@ window.i18nMessages = {
             cant_change_status_game_has_been_started: 'Cannot change status - game has been started',
             you_should_be_owner: 'You should be owner',
             errorGameAlreadyDeleted: 'Game has already been deleted',
+            invalid_game_rules: 'Invalid game rules',
+            invalid_team: 'Invalid team',
+            invalid_match_settings: 'Invalid match settings',
+            teams_are_not_equal: 'Teams should have equal number of players',
+            game_seed_is_not_allowed: 'Game seed is not allowed',
+            nickname_is_taken: 'Nickname is already taken',
+            nickname_is_registered: 'Nickname is registered, enter password',
+            invalid_nickname: 'Invalid nickname',
+            password_is_too_short: 'Password is too short',
+            password_is_too_long: 'Password is too long',
+            too_many_login_attempts: 'Too many login attempts, try again a bit later',
+            invalid_credentials: 'Invalid nickname or password',
+            accounts_are_unavailable: 'Accounts are unavailable, try again later',
+            session_cannot_be_resumed: 'Session cannot be resumed',
+            game_is_not_found: 'Game is not found',
+            replay_is_not_allowed_in_room: 'Leave the room to watch replays',
+            invalid_bot_level: 'Unknown level of the bot',
         },
         error: 'Error',
         info_messages: {
@@ -72,6 +107,8 @@ window.i18nMessages = {
             an_online_multiplayer_card_game: 'Многопользовательская карточная онлайн игра',
             nickname: 'Псевдоним',
             play: 'Играть',
+            password: 'Пароль',
+            register: 'Регистрация',
             websockets_are_not_supported: 'Извините, вы не сможете играть, потому что ваше устройство не поддерживает WebSocket',
             disconnected: 'Отключены',
             durak_an_online_multiplayer_card_game: 'Дурак - многопользовательская карточная онлайн игра'
@@ -82,6 +119,14 @@ window.i18nMessages = {
             your_nickname: 'Ваш псевдоним',
             your_id: 'Ваш ID',
             rooms: 'Комнаты',
+            week_leaderboard: 'Лидеры недели',
+            my_last_games: 'Мои последние игры',
+            lost: 'проиграл',
+            replay: 'Повтор',
+            replay_play: 'Воспроизвести',
+            replay_pause: 'Пауза',
+            replay_speed: 'Скорость',
+            replay_close: 'Закрыть повтор',
             room: 'Комната',
             here: 'Вы здесь',
             your_are_owner: 'вы создатель',
@@ -99,10 +144,18 @@ window.i18nMessages = {
             complete_game: 'Завершить игру',
             game: 'Игра',
             add_bot: 'Добавить бота',
+            bot_levels: {
+                easy: 'лёгкий',
+                normal: 'средний',
+                hard: 'сложный',
+                expert: 'эксперт',
+                trained: 'обученный на партиях',
+            },
             remove_bots: 'Удалить ботов',
         },
         game: {
             pick_up: 'Взять',
+            transfer: 'Перевести',
             complete: 'Готово',
             is_looser: 'проиграл',
             draw: 'ничья',
@@ -126,6 +179,23 @@ window.i18nMessages = {
             cant_change_status_game_has_been_started: 'Нельзя изменить статус: игра уже началась',
             you_should_be_owner: 'Вы должны быть создателем',
             errorGameAlreadyDeleted: 'Игра уже удалена',
+            invalid_game_rules: 'Неверные правила игры',
+            invalid_team: 'Неверная команда',
+            invalid_match_settings: 'Неверные настройки матча',
+            teams_are_not_equal: 'В командах должно быть одинаковое число игроков',
+            game_seed_is_not_allowed: 'Выбор зерна игры запрещен',
+            nickname_is_taken: 'Псевдоним уже занят',
+            nickname_is_registered: 'Псевдоним зарегистрирован, введите пароль',
+            invalid_nickname: 'Неверный псевдоним',
+            password_is_too_short: 'Слишком короткий пароль',
+            password_is_too_long: 'Слишком длинный пароль',
+            too_many_login_attempts: 'Слишком много попыток входа, попробуйте чуть позже',
+            invalid_credentials: 'Неверный псевдоним или пароль',
+            accounts_are_unavailable: 'Аккаунты недоступны, попробуйте позже',
+            session_cannot_be_resumed: 'Сессию нельзя восстановить',
+            game_is_not_found: 'Игра не найдена',
+            replay_is_not_allowed_in_room: 'Покиньте комнату, чтобы смотреть повторы',
+            invalid_bot_level: 'Неизвестный уровень бота',
         },
         error: 'Ошибка',
         info_messages: {
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Periods of leaderboards
+const (
+	LeaderboardPeriodDay     = "day"
+	LeaderboardPeriodWeek    = "week"
+	LeaderboardPeriodAllTime = "allTime"
+)
+
+// Orders of leaderboards
+const (
+	LeaderboardOrderRating   = "rating"
+	LeaderboardOrderWinRatio = "winRatio"
+)
+
+// Parameters of leaderboards
+const (
+	LeaderboardWeekDays = 7
+	// Minimal number of games in the period to get into the leaderboard
+	LeaderboardMinGames = 3
+	LeaderboardSize     = 20
+)
+
+// Leaderboard contains best players of the period
+type Leaderboard struct {
+	Period  string              `json:"period"`
+	OrderBy string              `json:"orderBy"`
+	Entries []*LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardEntry contains results of a player in the period
+type LeaderboardEntry struct {
+	Place       int     `json:"place"`
+	AccountId   uint64  `json:"accountId"`
+	Nickname    string  `json:"nickname"`
+	Rating      int     `json:"rating"`
+	GamesPlayed int     `json:"gamesPlayed"`
+	Losses      int     `json:"losses"`
+	WinRatio    float64 `json:"winRatio"`
+}
+
+func isValidLeaderboardPeriod(period string) bool {
+	return period == LeaderboardPeriodDay || period == LeaderboardPeriodWeek || period == LeaderboardPeriodAllTime
+}
+
+func isValidLeaderboardOrder(orderBy string) bool {
+	return orderBy == LeaderboardOrderRating || orderBy == LeaderboardOrderWinRatio
+}
+
+// Builds the leaderboard from stats of accounts. Only accounts have stats, so bots never get there.
+// Ratings are current ones, periods select players who played enough games in them.
+func (l *Lobby) getLeaderboard(period string, orderBy string, now time.Time) *Leaderboard {
+	entries := make([]*LeaderboardEntry, 0)
+	for _, stats := range l.statsStore.GetAllStats() {
+		periodStats := &PeriodStats{GamesPlayed: stats.GamesPlayed, Losses: stats.Losses}
+		if period == LeaderboardPeriodDay {
+			periodStats = stats.getPeriodStats(now, 1)
+		} else if period == LeaderboardPeriodWeek {
+			periodStats = stats.getPeriodStats(now, LeaderboardWeekDays)
+		}
+		if periodStats.GamesPlayed < LeaderboardMinGames {
+			continue
+		}
+		account := l.accountStore.GetAccount(stats.AccountId)
+		if account == nil {
+			continue
+		}
+		rating := getRatingToShow(l.ratingStore.GetRating(stats.AccountId))
+		if orderBy == LeaderboardOrderRating && rating == 0 {
+			continue
+		}
+		entries = append(entries, &LeaderboardEntry{
+			AccountId:   stats.AccountId,
+			Nickname:    account.Nickname,
+			Rating:      rating,
+			GamesPlayed: periodStats.GamesPlayed,
+			Losses:      periodStats.Losses,
+			WinRatio:    float64(periodStats.GamesPlayed-periodStats.Losses) / float64(periodStats.GamesPlayed),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if orderBy == LeaderboardOrderWinRatio && a.WinRatio != b.WinRatio {
+			return a.WinRatio > b.WinRatio
+		}
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		if a.GamesPlayed != b.GamesPlayed {
+			return a.GamesPlayed > b.GamesPlayed
+		}
+		return a.AccountId < b.AccountId
+	})
+
+	if len(entries) > LeaderboardSize {
+		entries = entries[:LeaderboardSize]
+	}
+	for i, entry := range entries {
+		entry.Place = i + 1
+	}
+
+	return &Leaderboard{
+		Period:  period,
+		OrderBy: orderBy,
+		Entries: entries,
+	}
+}
+
+// Returns leaderboards of all periods and orders
+func (l *Lobby) getAllLeaderboards(now time.Time) []*Leaderboard {
+	leaderboards := make([]*Leaderboard, 0)
+	for _, period := range []string{LeaderboardPeriodDay, LeaderboardPeriodWeek, LeaderboardPeriodAllTime} {
+		for _, orderBy := range []string{LeaderboardOrderRating, LeaderboardOrderWinRatio} {
+			leaderboards = append(leaderboards, l.getLeaderboard(period, orderBy, now))
+		}
+	}
+	return leaderboards
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLobbyWithLeaderboard(t *testing.T, now time.Time) *Lobby {
+	accountStore, _ := newTestAccountFileStore(t)
+	ratingStore, _ := newTestRatingFileStore(t)
+	statsStore, _ := newTestStatsFileStore(t)
+	lobby := newLobby(&TestGameLogger{}, accountStore, ratingStore, statsStore)
+	for _, nickname := range []string{"Alice", "Bob", "Carol"} {
+		accountStore.Register(nickname, "secret1")
+	}
+	ratingStore.ratings[1] = &PlayerRating{AccountId: 1, Rating: 1600}
+	ratingStore.ratings[2] = &PlayerRating{AccountId: 2, Rating: 1550}
+	ratingStore.ratings[3] = &PlayerRating{AccountId: 3, Rating: 1700}
+
+	today := getStatsDay(now)
+	threeDaysAgo := getStatsDay(now.AddDate(0, 0, -3))
+	statsStore.stats[1] = &PlayerStats{AccountId: 1, GamesPlayed: 20, Losses: 10, RecentDays: map[string]*PeriodStats{
+		today:        {GamesPlayed: 3, Losses: 0},
+		threeDaysAgo: {GamesPlayed: 2, Losses: 2},
+	}}
+	statsStore.stats[2] = &PlayerStats{AccountId: 2, GamesPlayed: 10, Losses: 1, RecentDays: map[string]*PeriodStats{
+		threeDaysAgo: {GamesPlayed: 4, Losses: 1},
+	}}
+	statsStore.stats[3] = &PlayerStats{AccountId: 3, GamesPlayed: 2, Losses: 0, RecentDays: map[string]*PeriodStats{
+		today: {GamesPlayed: 2, Losses: 0},
+	}}
+	return lobby
+}
+
+func getTestLeaderboardNicknames(leaderboard *Leaderboard) []string {
+	nicknames := make([]string, 0)
+	for _, entry := range leaderboard.Entries {
+		nicknames = append(nicknames, entry.Nickname)
+	}
+	return nicknames
+}
+
+func TestLeaderboardPeriodsAndOrders(t *testing.T) {
+	now := time.Now()
+	lobby := newTestLobbyWithLeaderboard(t, now)
+
+	assert := assert.New(t)
+	assert.Equal([]string{"Alice"}, getTestLeaderboardNicknames(lobby.getLeaderboard(LeaderboardPeriodDay, LeaderboardOrderRating, now)))
+	assert.Equal([]string{"Alice", "Bob"}, getTestLeaderboardNicknames(lobby.getLeaderboard(LeaderboardPeriodWeek, LeaderboardOrderRating, now)))
+	assert.Equal([]string{"Bob", "Alice"}, getTestLeaderboardNicknames(lobby.getLeaderboard(LeaderboardPeriodWeek, LeaderboardOrderWinRatio, now)))
+	assert.Equal([]string{"Bob", "Alice"}, getTestLeaderboardNicknames(lobby.getLeaderboard(LeaderboardPeriodAllTime, LeaderboardOrderWinRatio, now)))
+
+	weekLeaderboard := lobby.getLeaderboard(LeaderboardPeriodWeek, LeaderboardOrderRating, now)
+	assert.Equal(&LeaderboardEntry{
+		Place:       1,
+		AccountId:   1,
+		Nickname:    "Alice",
+		Rating:      1600,
+		GamesPlayed: 5,
+		Losses:      2,
+		WinRatio:    0.6,
+	}, weekLeaderboard.Entries[0])
+
+	// The next week only results of the last days are counted
+	assert.Empty(lobby.getLeaderboard(LeaderboardPeriodWeek, LeaderboardOrderRating, now.AddDate(0, 0, 7)).Entries)
+}
+
+func TestStatsForgetOldDays(t *testing.T) {
+	now := time.Now()
+	stats := &PlayerStats{RecentDays: map[string]*PeriodStats{
+		getStatsDay(now.AddDate(0, 0, -LeaderboardWeekDays)): {GamesPlayed: 1},
+		getStatsDay(now.AddDate(0, 0, -1)):                   {GamesPlayed: 1},
+	}}
+	stats.removeOldDays(now)
+
+	assert.Len(t, stats.RecentDays, 1)
+}
+
+func TestApiLeaderboards(t *testing.T) {
+	lobby := newTestLobbyWithLeaderboard(t, time.Now())
+
+	recorder := httptest.NewRecorder()
+	serveApiLeaderboards(lobby, recorder, httptest.NewRequest("GET", "/api/leaderboards?period=week&orderBy=winRatio", nil))
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, recorder.Code)
+	var leaderboard Leaderboard
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &leaderboard))
+	assert.Equal(LeaderboardPeriodWeek, leaderboard.Period)
+	assert.Equal([]string{"Bob", "Alice"}, getTestLeaderboardNicknames(&leaderboard))
+
+	recorder = httptest.NewRecorder()
+	serveApiLeaderboards(lobby, recorder, httptest.NewRequest("GET", "/api/leaderboards?period=year", nil))
+	assert.Equal(http.StatusBadRequest, recorder.Code)
+}
@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"sync/atomic"
+	"time"
 )
 
+// ReconnectGraceSeconds is time to hold the seat of a player in game after the connection dropped
+const ReconnectGraceSeconds = 60
+
 var lastClientId uint64
 var lastRoomId uint64
 
@@ -34,18 +39,65 @@ type Lobby struct {
 	rooms map[*Client]*Room
 
 	gameLogger GameLogger
+
+	accountStore AccountStore
+	ratingStore  RatingStore
+	statsStore   StatsStore
+
+	// Clients by session tokens including clients whose connection dropped during the game
+	sessions map[string]*Client
+
+	// Clients whose seats were held for too long
+	expiredSessions chan *Client
+
+	// Results of registrations and logins which are checked outside of the lobby goroutine
+	accountLogins chan *AccountLogin
+
+	loginThrottle *LoginThrottle
+
+	// Moves of bots to games of their rooms
+	botActions chan *BotAction
+
+	// Games whose loop ended after the end of the game
+	endedGames chan *Game
+
+	// Rooms whose match waits for the next game after the delay
+	matchNextGames chan *Room
+
+	// Requests to save state on shutdown
+	suspendRequests chan chan *LobbySnapshot
+
+	// Whether games can be started with a given random seed
+	allowGameSeed bool
+
+	// Dir of game logs to replay games
+	gameLogDir string
+
+	// Policy of trained bots, nil when it was not trained
+	botPolicy *BotPolicy
 }
 
-func newLobby(gameLogger GameLogger) *Lobby {
+func newLobby(gameLogger GameLogger, accountStore AccountStore, ratingStore RatingStore, statsStore StatsStore) *Lobby {
 	return &Lobby{
-		broadcast:      make(chan []byte),
-		register:       make(chan *Client),
-		unregister:     make(chan *Client),
-		clients:        make(map[*Client]bool),
-		clientCommands: make(chan *ClientCommand),
-		games:          make([]*Game, 0),
-		rooms:          make(map[*Client]*Room),
-		gameLogger:     gameLogger,
+		broadcast:       make(chan []byte),
+		register:        make(chan *Client),
+		unregister:      make(chan *Client),
+		clients:         make(map[*Client]bool),
+		clientCommands:  make(chan *ClientCommand),
+		games:           make([]*Game, 0),
+		rooms:           make(map[*Client]*Room),
+		gameLogger:      gameLogger,
+		accountStore:    accountStore,
+		ratingStore:     ratingStore,
+		statsStore:      statsStore,
+		sessions:        make(map[string]*Client),
+		expiredSessions: make(chan *Client),
+		accountLogins:   make(chan *AccountLogin),
+		loginThrottle:   newLoginThrottle(),
+		botActions:      make(chan *BotAction),
+		endedGames:      make(chan *Game),
+		matchNextGames:  make(chan *Room),
+		suspendRequests: make(chan chan *LobbySnapshot),
 	}
 }
 
@@ -78,9 +130,21 @@ func (l *Lobby) run() {
 			if _, ok := l.clients[client]; ok {
 				client.isValid = false
 				delete(l.clients, client)
-				l.onClientLeft(client)
+				l.onClientDisconnected(client)
 				close(client.send)
 			}
+		case client := <-l.expiredSessions:
+			l.onSessionExpired(client)
+		case accountLogin := <-l.accountLogins:
+			l.onAccountLogin(accountLogin)
+		case botAction := <-l.botActions:
+			l.onBotAction(botAction)
+		case game := <-l.endedGames:
+			l.onGameEnded(game)
+		case room := <-l.matchNextGames:
+			l.onMatchNextGame(room)
+		case snapshotResult := <-l.suspendRequests:
+			snapshotResult <- l.suspend()
 		case clientCommand := <-l.clientCommands:
 			l.onClientCommand(clientCommand)
 		}
@@ -93,19 +157,134 @@ func (l *Lobby) broadcastEvent(event interface{}) {
 }
 
 func (l *Lobby) onJoinCommand(c *Client, nickname string) {
+	if l.accountStore.IsNicknameRegistered(nickname) {
+		errEvent := &ClientCommandError{errorNicknameIsRegistered}
+		c.sendEvent(errEvent)
+		return
+	}
+	c.accountId = 0
+	c.rating = 0
+	l.joinClient(c, nickname)
+}
+
+func (l *Lobby) onRegisterCommand(c *Client, data *LobbyLoginCommandData) {
+	l.loginInBackground(c, false, func() (*Account, string, error) {
+		return l.accountStore.Register(data.Nickname, data.Password)
+	})
+}
+
+func (l *Lobby) onLoginCommand(c *Client, data *LobbyLoginCommandData) {
+	if data.Token != "" {
+		l.loginInBackground(c, false, func() (*Account, string, error) {
+			account, err := l.accountStore.LoginWithToken(data.Token)
+			return account, data.Token, err
+		})
+		return
+	}
+	if !l.loginThrottle.isAllowed(c.remoteHost, time.Now()) {
+		errEvent := &ClientCommandError{errorTooManyLoginAttempts}
+		c.sendEvent(errEvent)
+		return
+	}
+	l.loginInBackground(c, true, func() (*Account, string, error) {
+		return l.accountStore.LoginWithPassword(data.Nickname, data.Password)
+	})
+}
+
+// Checks credentials in another goroutine because hashing of passwords is slow and would stall all clients.
+// The result is sent back to the lobby. The client waits for the result before the next try.
+func (l *Lobby) loginInBackground(c *Client, isByPassword bool, login func() (*Account, string, error)) {
+	if c.isLoggingIn {
+		errEvent := &ClientCommandError{errorTooManyLoginAttempts}
+		c.sendEvent(errEvent)
+		return
+	}
+	c.isLoggingIn = true
+	go func() {
+		account, token, err := login()
+		l.accountLogins <- &AccountLogin{client: c, isByPassword: isByPassword, account: account, token: token, err: err}
+	}()
+}
+
+// Joins the client to the lobby under the verified account or sends the error of the account store
+func (l *Lobby) onAccountLogin(accountLogin *AccountLogin) {
+	c := accountLogin.client
+	c.isLoggingIn = false
+	if accountLogin.isByPassword && accountLogin.err == errAccountInvalidLogin {
+		l.loginThrottle.addFailure(c.remoteHost, time.Now())
+	}
+	if _, ok := l.clients[c]; !ok {
+		// Connection dropped during the check
+		return
+	}
+	if accountLogin.err != nil {
+		errorMessage := accountLogin.err.Error()
+		if !isAccountErrorForClient(accountLogin.err) {
+			log.Printf("Account store error: %s", accountLogin.err)
+			errorMessage = errorAccountsAreUnavailable
+		}
+		errEvent := &ClientCommandError{errorMessage}
+		c.sendEvent(errEvent)
+		return
+	}
+
+	account := accountLogin.account
+	c.accountId = account.Id
+	c.rating = getRatingToShow(l.ratingStore.GetRating(account.Id))
+	loggedInEvent := &ClientLoggedInEvent{
+		AccountId: account.Id,
+		Nickname:  account.Nickname,
+		Token:     accountLogin.token,
+	}
+	c.sendEvent(loggedInEvent)
+	l.joinClient(c, account.Nickname)
+}
+
+// Errors of the account store about credentials are sent to the client, other errors are logged
+func isAccountErrorForClient(err error) bool {
+	switch err {
+	case errAccountNicknameIsTaken, errAccountInvalidLogin, errAccountInvalidNickname,
+		errAccountPasswordTooShort, errAccountPasswordTooLong:
+		return true
+	}
+	return false
+}
+
+func (l *Lobby) joinClient(c *Client, nickname string) {
 	c.nickname = nickname
+	c.hasJoined = true
 
 	broadcastEvent := &ClientBroadCastJoinedEvent{
-		Id:       c.id,
-		Nickname: c.nickname,
+		Id:        c.id,
+		Nickname:  c.nickname,
+		AccountId: c.accountId,
+		Rating:    c.rating,
 	}
 	l.broadcastEvent(broadcastEvent)
 
+	delete(l.sessions, c.sessionToken)
+	sessionToken, err := generateRandomHex(LoginTokenSize)
+	if err != nil {
+		log.Printf("Cannot generate session token: %s", err)
+	} else {
+		c.sessionToken = sessionToken
+		l.sessions[sessionToken] = c
+	}
+
+	l.sendJoinedEvent(c)
+
+	leaderboardEvent := &LeaderboardEvent{Leaderboards: l.getAllLeaderboards(time.Now())}
+	c.sendEvent(leaderboardEvent)
+}
+
+func (l *Lobby) sendJoinedEvent(c *Client) {
 	clientsInList := make([]*ClientInList, 0)
 	for client := range l.clients {
 		clientInList := &ClientInList{
-			Id:       client.id,
-			Nickname: client.Nickname(),
+			Id:        client.id,
+			Nickname:  client.Nickname(),
+			AccountId: client.accountId,
+			Rating:    client.rating,
 		}
 		clientsInList = append(clientsInList, clientInList)
 	}
@@ -117,15 +296,182 @@ func (l *Lobby) onJoinCommand(c *Client, nickname string) {
 	}
 
 	event := &ClientJoinedEvent{
-		YourId:       c.id,
-		YourNickname: nickname,
-		Clients:      clientsInList,
-		Rooms:        roomsInList,
+		YourId:        c.id,
+		YourNickname:  c.nickname,
+		YourAccountId: c.accountId,
+		YourRating:    c.rating,
+		Clients:       clientsInList,
+		Rooms:         roomsInList,
+		SessionToken:  c.sessionToken,
 	}
 	c.sendEvent(event)
 }
 
+// Holds the seat of the player in the running game for a while, other clients leave at once
+func (l *Lobby) onClientDisconnected(client *Client) {
+	l.closeReplay(client)
+	room := client.room
+	if client.sessionToken == "" || room == nil || room.game == nil || !room.game.dropConnection(client) {
+		delete(l.sessions, client.sessionToken)
+		l.onClientLeft(client)
+		return
+	}
+
+	log.Printf("Holding seat of client %s for %d seconds", client.Nickname(), ReconnectGraceSeconds)
+	client.reconnectTimer = time.AfterFunc(time.Second*ReconnectGraceSeconds, func() {
+		l.expiredSessions <- client
+	})
+}
+
+// Starts the next game of the match unless the room was deleted during the delay
+func (l *Lobby) onMatchNextGame(room *Room) {
+	if l.hasRoom(room) {
+		room.startNextMatchGame()
+	}
+}
+
+// Checks if the room was not deleted
+func (l *Lobby) hasRoom(room *Room) bool {
+	for _, r := range l.rooms {
+		if r == room {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lobby) onSessionExpired(client *Client) {
+	if l.sessions[client.sessionToken] != client {
+		// Session was resumed by a new connection
+		return
+	}
+	log.Printf("Session of client %s expired", client.Nickname())
+	delete(l.sessions, client.sessionToken)
+	l.onClientLeft(client)
+}
+
+// Reattaches the new connection to the client whose connection dropped, so he keeps his seat in game
+func (l *Lobby) onResumeCommand(c *Client, sessionToken string) {
+	heldClient, ok := l.sessions[sessionToken]
+	// Resume has to be the first command, other clients know the client who joined by its own id
+	if !ok || heldClient.isValid || heldClient == c || c.hasJoined {
+		errEvent := &ClientCommandError{errorSessionCannotBeResumed}
+		c.sendEvent(errEvent)
+		return
+	}
+	if heldClient.reconnectTimer != nil {
+		heldClient.reconnectTimer.Stop()
+	}
+
+	delete(l.sessions, c.sessionToken)
+	c.hasJoined = true
+	c.id = heldClient.id
+	c.nickname = heldClient.nickname
+	c.accountId = heldClient.accountId
+	c.rating = heldClient.rating
+	c.sessionToken = sessionToken
+	l.sessions[sessionToken] = c
+
+	room := heldClient.room
+	if room != nil {
+		if l.rooms[heldClient] == room {
+			delete(l.rooms, heldClient)
+			l.rooms[c] = room
+		}
+		room.replaceClient(heldClient, c)
+	}
+
+	l.sendJoinedEvent(c)
+	if room != nil {
+		roomJoinedEvent := RoomJoinedEvent{room.toRoomInfo()}
+		c.sendEvent(roomJoinedEvent)
+		if room.game != nil {
+			room.game.resumeConnection(heldClient, c)
+		}
+	}
+}
+
+// Updates stats, ratings and the room of the ended game unless the room was deleted
+func (l *Lobby) onGameEnded(game *Game) {
+	// Deleted games are not counted
+	if game.state.endReason != GameEndReasonDeleted {
+		l.updateStats(game)
+		l.updateRatings(game)
+	}
+	if l.hasRoom(game.room) {
+		game.room.onGameEnded(game)
+	}
+}
+
+func (l *Lobby) updateStats(game *Game) {
+	places := game.state.getPlaces()
+	gameSeconds := int(time.Since(game.startedAt).Seconds())
+	results := make([]*PlayerGameResult, 0)
+	for index, p := range game.players {
+		place, ok := places[index]
+		if !ok || p.AccountId == 0 {
+			continue
+		}
+		results = append(results, &PlayerGameResult{
+			AccountId:   p.AccountId,
+			Place:       place,
+			IsLoser:     game.state.isLoser(index),
+			GameSeconds: gameSeconds,
+			Stats:       game.getPlayerState(index).stats,
+		})
+	}
+	if len(results) == 0 {
+		return
+	}
+	// Stats file is rewritten in background, so the lobby is not blocked by it
+	go func() {
+		if err := l.statsStore.AddGameResults(results); err != nil {
+			log.Printf("Cannot save stats: %s", err)
+		}
+	}()
+}
+
+// Updates ratings of registered players by places in the ended game. Games with bots are not rated.
+func (l *Lobby) updateRatings(game *Game) {
+	places := game.state.getPlaces()
+	results := make([]*RatingGameResult, 0)
+	clients := make(map[uint64]*Client, 0)
+	for index, p := range game.players {
+		if p.isBot() {
+			return
+		}
+		place, ok := places[index]
+		client, isClient := p.client.(*Client)
+		if !ok || !isClient || client.accountId == 0 {
+			// Spectators and guests are not rated
+			continue
+		}
+		results = append(results, &RatingGameResult{AccountId: client.accountId, Place: place})
+		clients[client.accountId] = client
+	}
+	if len(results) < 2 {
+		return
+	}
+
+	ratings, err := l.ratingStore.AddGameResults(results)
+	if err != nil {
+		log.Printf("Cannot save ratings: %s", err)
+	}
+	for accountId, rating := range ratings {
+		client := clients[accountId]
+		newRating := getRatingToShow(rating)
+		ratingUpdatedEvent := &ClientRatingUpdatedEvent{
+			Id:     client.id,
+			Rating: newRating,
+			Change: newRating - client.rating,
+		}
+		client.rating = newRating
+		l.broadcastEvent(ratingUpdatedEvent)
+	}
+}
+
 func (l *Lobby) onClientLeft(client *Client) {
+	l.closeReplay(client)
 	room := client.room
 	if room != nil {
 		l.onLeftRoom(client, room)
@@ -136,6 +482,37 @@ func (l *Lobby) onClientLeft(client *Client) {
 	l.broadcastEvent(leftEvent)
 }
 
+// Opens the replay room with the logged game, the client should leave the room to watch replays
+func (l *Lobby) onOpenReplayCommand(c *Client, gameId string) {
+	if c.room != nil {
+		errEvent := &ClientCommandError{errorReplayIsNotAllowedInRoom}
+		c.sendEvent(errEvent)
+		return
+	}
+	history, err := readGameHistory(l.gameLogDir, gameId)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cannot read game %s for replay: %s", gameId, err)
+		}
+		errEvent := &ClientCommandError{errorGameIsNotFound}
+		c.sendEvent(errEvent)
+		return
+	}
+
+	l.closeReplay(c)
+	c.replayRoom = newReplayRoom(c, newGameReplay(history))
+	go c.replayRoom.run()
+}
+
+func (l *Lobby) closeReplay(c *Client) {
+	if c.replayRoom == nil {
+		return
+	}
+	c.replayRoom.close()
+	c.replayRoom = nil
+	c.sendEvent(&ReplayClosedEvent{})
+}
+
 func (l *Lobby) onCreateNewRoomCommand(c *Client) {
 	_, roomExists := l.rooms[c]
 	if roomExists {
@@ -144,6 +521,7 @@ func (l *Lobby) onCreateNewRoomCommand(c *Client) {
 		return
 	}
 
+	l.closeReplay(c)
 	oldRoomJoined := c.room
 	if oldRoomJoined != nil {
 		l.onLeftRoom(c, oldRoomJoined)
@@ -199,6 +577,7 @@ func (l *Lobby) onJoinRoomCommand(c *Client, roomId uint64) {
 	if oldRoomJoined != nil && oldRoomJoined.Id() == roomId {
 		return
 	}
+	l.closeReplay(c)
 	if oldRoomJoined != nil {
 		l.onLeftRoom(c, oldRoomJoined)
 	}
@@ -222,6 +601,24 @@ func (l *Lobby) onClientCommand(cc *ClientCommand) {
 				return
 			}
 			l.onJoinCommand(cc.client, nickname)
+		} else if cc.SubType == ClientCommandLobbySubTypeRegister {
+			var loginData LobbyLoginCommandData
+			if err := json.Unmarshal(cc.Data, &loginData); err != nil {
+				return
+			}
+			l.onRegisterCommand(cc.client, &loginData)
+		} else if cc.SubType == ClientCommandLobbySubTypeLogin {
+			var loginData LobbyLoginCommandData
+			if err := json.Unmarshal(cc.Data, &loginData); err != nil {
+				return
+			}
+			l.onLoginCommand(cc.client, &loginData)
+		} else if cc.SubType == ClientCommandLobbySubTypeResume {
+			var sessionToken string
+			if err := json.Unmarshal(cc.Data, &sessionToken); err != nil {
+				return
+			}
+			l.onResumeCommand(cc.client, sessionToken)
 		} else if cc.SubType == ClientCommandLobbySubTypeCreateRoom {
 			l.onCreateNewRoomCommand(cc.client)
 		} else if cc.SubType == ClientCommandLobbySubTypeJoinRoom {
@@ -231,6 +628,24 @@ func (l *Lobby) onClientCommand(cc *ClientCommand) {
 			}
 			l.onJoinRoomCommand(cc.client, roomId)
 		}
+	} else if cc.Type == ClientCommandTypeReplay {
+		if cc.SubType == ClientCommandReplaySubTypeOpen {
+			var gameId string
+			if err := json.Unmarshal(cc.Data, &gameId); err != nil {
+				return
+			}
+			l.onOpenReplayCommand(cc.client, gameId)
+		} else if cc.SubType == ClientCommandReplaySubTypeControl {
+			var controlData ReplayControlCommandData
+			if err := json.Unmarshal(cc.Data, &controlData); err != nil {
+				return
+			}
+			if cc.client.replayRoom != nil {
+				cc.client.replayRoom.control(&controlData)
+			}
+		} else if cc.SubType == ClientCommandReplaySubTypeClose {
+			l.closeReplay(cc.client)
+		}
 	} else if cc.Type == ClientCommandTypeGame {
 		l.dispatchGameEvent(cc)
 	} else if cc.Type == ClientCommandTypeRoom {
@@ -249,7 +664,7 @@ func (l *Lobby) dispatchGameEvent(cc *ClientCommand) {
 		return
 	}
 	game := cc.client.room.game
-	if game.status != GameStatusPlaying {
+	if game.getStatus() != GameStatusPlaying {
 		return
 	}
 
@@ -272,6 +687,12 @@ func (l *Lobby) dispatchGameEvent(cc *ClientCommand) {
 			return
 		}
 		playerAction = &PlayerAction{Name: PlayerActionNameDefend, Data: defendActionData, player: player}
+	} else if cc.SubType == ClientCommandGameSubTypeTransfer {
+		var transferActionData TransferActionData
+		if err := json.Unmarshal(cc.Data, &transferActionData); err != nil {
+			return
+		}
+		playerAction = &PlayerAction{Name: PlayerActionNameTransfer, Data: transferActionData, player: player}
 	} else if cc.SubType == ClientCommandGameSubTypePickUp {
 		playerAction = &PlayerAction{Name: PlayerActionNamePickUp, player: player}
 	} else if cc.SubType == ClientCommandGameSubTypeComplete {
@@ -279,7 +700,23 @@ func (l *Lobby) dispatchGameEvent(cc *ClientCommand) {
 	}
 
 	if playerAction != nil {
-		game.playerActions <- playerAction
+		game.sendAction(playerAction)
+	}
+}
+
+// Passes the move of the bot to the running game of its room
+func (l *Lobby) onBotAction(botAction *BotAction) {
+	game := botAction.bot.room.game
+	if game == nil || game.getStatus() != GameStatusPlaying {
+		log.Printf("BOT: Cannot send game action - game is not running")
+		return
+	}
+	for _, p := range game.players {
+		if p.client.Id() == botAction.bot.Id() {
+			botAction.action.player = p
+			game.sendAction(botAction.action)
+			return
+		}
 	}
 }
 
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLobbyWithGame() (lobby *Lobby, room *Room, clients []*Client) {
+	lobby = newLobby(&TestGameLogger{}, &AccountFileStore{accounts: make([]*Account, 0)}, &RatingFileStore{ratings: make(map[uint64]*PlayerRating, 0)}, &StatsFileStore{stats: make(map[uint64]*PlayerStats, 0)})
+	go func() {
+		for range lobby.broadcast {
+		}
+	}()
+
+	clients = []*Client{{id: 1, lobby: lobby, isValid: true}, {id: 2, lobby: lobby, isValid: true}}
+	for i, c := range clients {
+		lobby.clients[c] = true
+		lobby.joinClient(c, []string{"Alice", "Bob"}[i])
+	}
+	lobby.onCreateNewRoomCommand(clients[0])
+	room = lobby.rooms[clients[0]]
+	lobby.onJoinRoomCommand(clients[1], room.Id())
+
+	players := []*Player{newPlayer(clients[0], true), newPlayer(clients[1], true)}
+	room.game = newGame(room, players, &TestGameLogger{}, newGameRules())
+	room.game.state.deck = newDeck(DeckSize36)
+	room.game.state.trumpCard = &Card{"6", "♠"}
+	room.game.state.trumpSuit = room.game.state.trumpCard.Suit
+	room.game.state.defenderIndex = 1
+	room.game.status = GameStatusPlaying
+
+	return lobby, room, clients
+}
+
+func disconnectTestClient(lobby *Lobby, client *Client) {
+	client.isValid = false
+	delete(lobby.clients, client)
+	lobby.onClientDisconnected(client)
+	if client.reconnectTimer != nil {
+		client.reconnectTimer.Stop()
+	}
+}
+
+func TestResumeSessionKeepsSeat(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	go room.game.loop()
+	sessionToken := clients[0].sessionToken
+	disconnectTestClient(lobby, clients[0])
+
+	assert := assert.New(t)
+	assert.Equal(GameStatusPlaying, room.game.status)
+
+	newClient := &Client{id: 3, lobby: lobby, isValid: true}
+	lobby.clients[newClient] = true
+	lobby.onResumeCommand(newClient, sessionToken)
+
+	assert.Equal(uint64(1), newClient.Id())
+	assert.Equal("Alice", newClient.Nickname())
+	assert.Equal(room, newClient.room)
+	assert.Equal(room, lobby.rooms[newClient])
+	assert.Equal(newClient, room.game.players[0].client)
+	assert.Equal(newClient, room.owner.client)
+
+	lobby.onSessionExpired(clients[0])
+	assert.Equal(GameStatusPlaying, room.game.status)
+}
+
+func TestExpiredSessionEndsGame(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	go room.game.loop()
+	disconnectTestClient(lobby, clients[1])
+	lobby.onSessionExpired(clients[1])
+
+	assert := assert.New(t)
+	assert.Equal(room.game, <-lobby.endedGames)
+	assert.Equal(GameStatusEnd, room.game.status)
+	assert.Equal(GameEndReasonPlayerLeft, room.game.state.endReason)
+	assert.Equal(1, room.game.state.loserIndex)
+}
+
+func TestResumeUnknownSession(t *testing.T) {
+	lobby, _, clients := newTestLobbyWithGame()
+	newClient := &Client{id: 3, lobby: lobby, isValid: true}
+	lobby.onResumeCommand(newClient, clients[0].sessionToken)
+
+	assert.Equal(t, uint64(3), newClient.Id())
+}
+
+func TestResumeAfterJoinIsRefused(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	go room.game.loop()
+	sessionToken := clients[0].sessionToken
+	disconnectTestClient(lobby, clients[0])
+
+	newClient := &Client{id: 3, lobby: lobby, isValid: true, send: make(chan []byte, 10)}
+	lobby.clients[newClient] = true
+	lobby.joinClient(newClient, "Carol")
+	lobby.onResumeCommand(newClient, sessionToken)
+
+	assert := assert.New(t)
+	assert.Equal(uint64(3), newClient.Id())
+	assert.Equal("Carol", newClient.Nickname())
+	assert.Equal(clients[0], room.game.players[0].client)
+}
+
+func TestEndedGameIsCountedInMatchByLobby(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	room.match = newMatch(&MatchSettings{Mode: MatchModeGames, Target: 1})
+	game := room.game
+	game.state.deck = &Deck{cards: []*Card{}}
+	game.state.players[0].cards = []*Card{{"7", "♥"}}
+	game.state.players[1].cards = []*Card{{"9", "♥"}, {"10", "♥"}}
+	go game.loop()
+
+	commands := []*ClientCommand{
+		{SubType: ClientCommandGameSubTypeAttack, Data: []byte(`{"card":{"value":"7","suit":"♥"}}`), client: clients[0]},
+		{SubType: ClientCommandGameSubTypeDefend, Data: []byte(`{"attackingCard":{"value":"7","suit":"♥"},"defendingCard":{"value":"9","suit":"♥"}}`), client: clients[1]},
+		{SubType: ClientCommandGameSubTypeComplete, client: clients[0]},
+		{SubType: ClientCommandGameSubTypeComplete, client: clients[1]},
+	}
+	for _, cc := range commands {
+		cc.Type = ClientCommandTypeGame
+		lobby.onClientCommand(cc)
+	}
+
+	endedGame := <-lobby.endedGames
+	assert := assert.New(t)
+	assert.Equal(game, endedGame)
+	assert.Equal(0, room.match.gamesPlayed)
+
+	lobby.onGameEnded(endedGame)
+	assert.Equal(1, room.match.gamesPlayed)
+	assert.Equal(1, room.match.scores[clients[1].Id()].Losses)
+	assert.True(room.match.isOver)
+}
+
+func TestDeleteRunningGame(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	game := room.game
+	go game.loop()
+	go func() {
+		for range lobby.endedGames {
+		}
+	}()
+	room.onDeleteGameCommand(clients[0])
+
+	assert := assert.New(t)
+	assert.Nil(room.game)
+	assert.Equal(GameStatusEnd, game.status)
+	assert.Equal(GameEndReasonDeleted, game.state.endReason)
+}
+
+func TestBotActionOfDeletedGame(t *testing.T) {
+	lobby, room, _ := newTestLobbyWithGame()
+	bot := &BotClient{id: 5, room: room}
+	room.game = nil
+
+	lobby.onBotAction(&BotAction{bot: bot, action: &PlayerAction{Name: PlayerActionNamePickUp}})
+}
+
+func TestMatchNextGameOfDeletedRoom(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	room.match = newMatch(&MatchSettings{Mode: MatchModeGames, Target: 3})
+	room.game.status = GameStatusEnd
+	endedGame := room.game
+	delete(lobby.rooms, clients[0])
+
+	lobby.onMatchNextGame(room)
+
+	assert.Equal(t, endedGame, room.game)
+}
@@ -0,0 +1,64 @@
+package main
+
+import "time"
+
+// Delays of logins from the address after wrong passwords, the delay doubles with each wrong password
+const (
+	LoginThrottleMinDelay = time.Second
+	LoginThrottleMaxDelay = time.Minute
+	// Wrong passwords are forgotten after the time without new ones
+	LoginThrottleResetTime = 15 * time.Minute
+)
+
+// LoginThrottle slows down guessing of passwords from the same address.
+// Accounts are never locked, so nobody can lock other players out of their accounts.
+type LoginThrottle struct {
+	failures map[string]*loginFailures
+}
+
+type loginFailures struct {
+	number int
+	lastAt time.Time
+}
+
+func newLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{
+		failures: make(map[string]*loginFailures, 0),
+	}
+}
+
+// Checks if the delay after the last wrong password from the address has passed
+func (t *LoginThrottle) isAllowed(address string, now time.Time) bool {
+	failures, ok := t.failures[address]
+	if !ok {
+		return true
+	}
+	return !now.Before(failures.lastAt.Add(getLoginDelay(failures.number)))
+}
+
+// Counts the wrong password from the address and forgets addresses without wrong passwords for a long time
+func (t *LoginThrottle) addFailure(address string, now time.Time) {
+	for a, f := range t.failures {
+		if now.Sub(f.lastAt) >= LoginThrottleResetTime {
+			delete(t.failures, a)
+		}
+	}
+	failures, ok := t.failures[address]
+	if !ok {
+		failures = &loginFailures{}
+		t.failures[address] = failures
+	}
+	failures.number++
+	failures.lastAt = now
+}
+
+func getLoginDelay(failuresNumber int) time.Duration {
+	delay := LoginThrottleMinDelay
+	for i := 1; i < failuresNumber && delay < LoginThrottleMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > LoginThrottleMaxDelay {
+		return LoginThrottleMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottleDoublesDelay(t *testing.T) {
+	throttle := newLoginThrottle()
+	now := time.Now()
+
+	assert := assert.New(t)
+	assert.True(throttle.isAllowed("10.0.0.1", now))
+	throttle.addFailure("10.0.0.1", now)
+	throttle.addFailure("10.0.0.1", now)
+	assert.False(throttle.isAllowed("10.0.0.1", now.Add(LoginThrottleMinDelay)))
+	assert.True(throttle.isAllowed("10.0.0.1", now.Add(2*LoginThrottleMinDelay)))
+	assert.True(throttle.isAllowed("10.0.0.2", now), "other addresses are not delayed")
+}
+
+func TestLoginThrottleMaxDelay(t *testing.T) {
+	assert.Equal(t, LoginThrottleMaxDelay, getLoginDelay(100))
+}
+
+func TestLoginThrottleForgetsOldFailures(t *testing.T) {
+	throttle := newLoginThrottle()
+	now := time.Now()
+	throttle.addFailure("10.0.0.1", now)
+	throttle.addFailure("10.0.0.2", now.Add(LoginThrottleResetTime))
+
+	_, ok := throttle.failures["10.0.0.1"]
+	assert.False(t, ok)
+}
+
+func TestLobbyThrottlesWrongPasswords(t *testing.T) {
+	lobby, _, _ := newTestLobbyWithGame()
+	store, _ := newTestAccountFileStore(t)
+	lobby.accountStore = store
+	store.Register("Carol", "secret1")
+	client := &Client{id: 3, lobby: lobby, isValid: true, send: make(chan []byte, 10), remoteHost: "10.0.0.1"}
+	lobby.clients[client] = true
+
+	lobby.onLoginCommand(client, &LobbyLoginCommandData{Nickname: "Carol", Password: "wrong password"})
+	lobby.onLoginCommand(client, &LobbyLoginCommandData{Nickname: "Carol", Password: "secret2"})
+	lobby.onAccountLogin(<-lobby.accountLogins)
+	lobby.onLoginCommand(client, &LobbyLoginCommandData{Nickname: "Carol", Password: "secret1"})
+
+	tooManyAttempts := `{"name":"ClientCommandError","data":{"message":"too_many_login_attempts"}}`
+	invalidCredentials := `{"name":"ClientCommandError","data":{"message":"invalid_credentials"}}`
+	assert := assert.New(t)
+	assert.Equal(tooManyAttempts, string(<-client.send), "the previous try is not checked yet")
+	assert.Equal(invalidCredentials, string(<-client.send))
+	assert.Equal(tooManyAttempts, string(<-client.send), "the address waits after the wrong password")
+
+	otherClient := &Client{id: 4, lobby: lobby, isValid: true, send: make(chan []byte, 10), remoteHost: "10.0.0.2"}
+	lobby.clients[otherClient] = true
+	lobby.onLoginCommand(otherClient, &LobbyLoginCommandData{Nickname: "Carol", Password: "secret1"})
+	lobby.onAccountLogin(<-lobby.accountLogins)
+	assert.Equal("Carol", otherClient.Nickname(), "the account is not locked")
+}
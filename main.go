@@ -5,13 +5,24 @@ import (
 	"flag"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var addr = flag.String("addr", "127.0.0.1:8007", "http service address")
 var serveFiles = flag.Bool("serveFiles", true, "use this app to serve static files (js, css, images)")
 var gameLogDir = flag.String("gameLogDir", "/var/log/durak", "dir to store game logs")
 var appEnv = flag.String("env", "local", "application environment: local, production")
+var dataDir = flag.String("dataDir", "/var/lib/durak", "dir to store accounts, ratings, stats and snapshot of rooms on shutdown")
+var gameLogFormat = flag.String("gameLogFormat", GameLogFormatText, "format of game logs: text, json")
+var verifyLog = flag.String("verifyGameLog", "", "check deck commitment in the given game log file and exit")
+var trainPolicy = flag.Bool("trainBotPolicy", false, "count decisions of players in game logs, save policy of trained bots to dataDir and exit")
 
 var indexPageContent []byte
 
@@ -31,8 +42,66 @@ func faviconHandler(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, "html/favicon.ico")
 }
 
+func verifyGameLogFile(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal("Open game log error: ", err)
+	}
+	defer f.Close()
+
+	verify := verifyGameLog
+	if strings.HasSuffix(path, ".jsonl") {
+		verify = verifyGameJsonLog
+	}
+	if err := verify(f); err != nil {
+		log.Fatal("Game log is not verified: ", err)
+	}
+	log.Println("Game log is verified: deck order matches commitment")
+}
+
+func trainBotPolicyFile(gameLogDir string, path string) {
+	policy, err := trainBotPolicy(gameLogDir)
+	if err != nil {
+		log.Fatal("Train bot policy error: ", err)
+	}
+	if err := policy.save(path); err != nil {
+		log.Fatal("Save bot policy error: ", err)
+	}
+	log.Printf("Saved bot policy with %d decisions from %d games to %s", policy.DecisionsNum, policy.GamesNum, path)
+}
+
+// Saves rooms and running games to snapshot when the server is stopped
+func suspendOnShutdown(lobby *Lobby, gameLogger BufferedGameLogger, snapshotPath string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	snapshotResult := make(chan *LobbySnapshot)
+	lobby.suspendRequests <- snapshotResult
+	snapshot := <-snapshotResult
+	if err := saveLobbySnapshot(snapshotPath, snapshot); err != nil {
+		log.Println("Save snapshot error: ", err)
+	} else {
+		log.Printf("Saved %d rooms to snapshot", len(snapshot.Rooms))
+	}
+	gameLogger.Wait()
+	os.Exit(0)
+}
+
 func main() {
 	flag.Parse()
+	// Global source is used for names of bots, games have their own sources
+	rand.Seed(time.Now().UnixNano())
+
+	if *verifyLog != "" {
+		verifyGameLogFile(*verifyLog)
+		return
+	}
+	botPolicyPath := filepath.Join(*dataDir, "bot_policy.json")
+	if *trainPolicy {
+		trainBotPolicyFile(*gameLogDir, botPolicyPath)
+		return
+	}
 
 	indexPageContentRaw, err := ioutil.ReadFile("html/index.html")
 	if err != nil {
@@ -45,12 +114,53 @@ func main() {
 	indexPageContent = bytes.Replace(indexPageContentRaw, []byte("%APP_ENV%"), []byte(*appEnv), 1)
 	indexPageContent = bytes.Replace(indexPageContent, []byte("%APP_VERSION%"), bytes.TrimSpace([]byte(version)), 2)
 
-	gameLogger := NewGameFileLogger(*gameLogDir, func(err error) {
+	logGameError := func(err error) {
 		log.Println(err)
-	})
+	}
+	var gameLogger BufferedGameLogger
+	switch *gameLogFormat {
+	case GameLogFormatText:
+		gameLogger = NewGameFileLogger(*gameLogDir, logGameError)
+	case GameLogFormatJson:
+		gameLogger = NewGameJsonLogger(*gameLogDir, logGameError)
+	default:
+		log.Fatal("Unknown game log format: ", *gameLogFormat)
+	}
+
+	accountStore, err := NewAccountFileStore(filepath.Join(*dataDir, "accounts.json"))
+	if err != nil {
+		log.Fatal("Load accounts error: ", err)
+	}
+
+	ratingStore, err := NewRatingFileStore(filepath.Join(*dataDir, "ratings.json"))
+	if err != nil {
+		log.Fatal("Load ratings error: ", err)
+	}
+
+	statsStore, err := NewStatsFileStore(filepath.Join(*dataDir, "stats.json"))
+	if err != nil {
+		log.Fatal("Load stats error: ", err)
+	}
+
+	lobby := newLobby(gameLogger, accountStore, ratingStore, statsStore)
+	lobby.allowGameSeed = *appEnv != "production"
+	lobby.gameLogDir = *gameLogDir
+	lobby.botPolicy, err = loadBotPolicy(botPolicyPath)
+	if err != nil {
+		log.Println("Load bot policy error: ", err)
+	}
+
+	snapshotPath := filepath.Join(*dataDir, "snapshot.json")
+	snapshot, err := loadLobbySnapshot(snapshotPath)
+	if err != nil {
+		log.Println("Load snapshot error: ", err)
+	} else if snapshot != nil {
+		log.Printf("Restoring %d rooms from snapshot", len(snapshot.Rooms))
+		lobby.restore(snapshot)
+	}
 
-	lobby := newLobby(gameLogger)
 	go lobby.run()
+	go suspendOnShutdown(lobby, gameLogger, snapshotPath)
 	http.HandleFunc("/", serveIndexPage)
 	if *serveFiles {
 		http.HandleFunc("/favicon.ico", faviconHandler)
@@ -61,6 +171,17 @@ func main() {
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		serveWs(lobby, w, r)
 	})
+	http.HandleFunc("/api/players/", func(w http.ResponseWriter, r *http.Request) {
+		serveApiPlayers(lobby, w, r)
+	})
+	serveGames := func(w http.ResponseWriter, r *http.Request) {
+		serveApiGames(*gameLogDir, w, r)
+	}
+	http.HandleFunc("/api/games", serveGames)
+	http.HandleFunc("/api/games/", serveGames)
+	http.HandleFunc("/api/leaderboards", func(w http.ResponseWriter, r *http.Request) {
+		serveApiLeaderboards(lobby, w, r)
+	})
 	log.Printf("Listening http://%s", *addr)
 	err = http.ListenAndServe(*addr, nil)
 	if err != nil {
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Modes of the match which define when the match is over.
+const (
+	MatchModeLosses = "losses"
+	MatchModeGames  = "games"
+
+	MatchNextGameDelaySeconds = 5
+)
+
+// MatchSettings contains target of the match series which is set by room owner.
+type MatchSettings struct {
+	Mode   string `json:"mode"`
+	Target int    `json:"target"`
+}
+
+// MatchScore contains cumulative results of a room member in the match.
+type MatchScore struct {
+	MemberId uint64      `json:"memberId"`
+	Nickname string      `json:"nickname"`
+	Games    int         `json:"games"`
+	Losses   int         `json:"losses"`
+	Places   map[int]int `json:"places"`
+}
+
+// Match represents series of games in the room with cumulative scoring.
+type Match struct {
+	settings     *MatchSettings
+	scores       map[uint64]*MatchScore
+	gamesPlayed  int
+	lastLoserIds []uint64
+	isOver       bool
+}
+
+func newMatch(settings *MatchSettings) *Match {
+	return &Match{
+		settings:     settings,
+		scores:       make(map[uint64]*MatchScore, 0),
+		lastLoserIds: make([]uint64, 0),
+	}
+}
+
+func (s *MatchSettings) validate() error {
+	if s.Mode != MatchModeLosses && s.Mode != MatchModeGames {
+		return fmt.Errorf("unknown match mode: %s", s.Mode)
+	}
+	if s.Target < 1 {
+		return fmt.Errorf("match target should be positive, got %d", s.Target)
+	}
+	return nil
+}
+
+func (m *Match) getScore(client ClientSender) *MatchScore {
+	score, ok := m.scores[client.Id()]
+	if !ok {
+		score = &MatchScore{
+			MemberId: client.Id(),
+			Nickname: client.Nickname(),
+			Places:   make(map[int]int, 0),
+		}
+		m.scores[client.Id()] = score
+	}
+	return score
+}
+
+// Adds results of the ended game to the tally and checks if the match is over
+func (m *Match) addGameResult(game *Game) {
+	m.gamesPlayed++
+	m.lastLoserIds = make([]uint64, 0)
+
+	places := game.state.getPlaces()
+	for index, p := range game.players {
+		place, ok := places[index]
+		if !ok {
+			// Spectator who joined during the game
+			continue
+		}
+		score := m.getScore(p.client)
+		score.Games++
+		score.Places[place]++
+		if game.state.isLoser(index) {
+			score.Losses++
+			m.lastLoserIds = append(m.lastLoserIds, p.client.Id())
+		}
+	}
+
+	switch m.settings.Mode {
+	case MatchModeGames:
+		m.isOver = m.gamesPlayed >= m.settings.Target
+	case MatchModeLosses:
+		for _, score := range m.scores {
+			if score.Losses >= m.settings.Target {
+				m.isOver = true
+			}
+		}
+	}
+}
+
+// Returns index of the player who loses the previous game of the match, he leads off the next game
+func (m *Match) getLeadingPlayerIndex(players []*Player) int {
+	for index, p := range players {
+		for _, loserId := range m.lastLoserIds {
+			if p.client.Id() == loserId {
+				return index
+			}
+		}
+	}
+	return -1
+}
+
+// Returns scores of the match, members with fewer losses go first
+func (m *Match) toScoreboardEvent() *RoomScoreboardEvent {
+	scores := make([]*MatchScore, 0)
+	for _, score := range m.scores {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Losses != scores[j].Losses {
+			return scores[i].Losses < scores[j].Losses
+		}
+		return scores[i].MemberId < scores[j].MemberId
+	})
+	return &RoomScoreboardEvent{
+		Match:       m.settings,
+		GamesPlayed: m.gamesPlayed,
+		Scores:      scores,
+		IsOver:      m.isOver,
+	}
+}
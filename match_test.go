@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEndedGame(loserIndex int, finishingOrder ...[]int) *Game {
+	game := newTestGame([]*Card{}, []*Card{}, []*Card{})
+	for _, p := range game.state.players {
+		p.isActive = false
+	}
+	game.state.players[loserIndex].isActive = true
+	game.state.finishingOrder = finishingOrder
+	game.state.loserIndex = loserIndex
+	game.state.isEnded = true
+	game.status = GameStatusEnd
+	return game
+}
+
+func TestMatchAddGameResult(t *testing.T) {
+	match := newMatch(&MatchSettings{Mode: MatchModeLosses, Target: 2})
+	match.addGameResult(newTestEndedGame(2, []int{0}, []int{1}))
+
+	assert := assert.New(t)
+	assert.Equal(1, match.gamesPlayed)
+	assert.Equal(1, match.scores[3].Losses)
+	assert.Equal(1, match.scores[3].Places[3])
+	assert.Equal(1, match.scores[1].Places[1])
+	assert.Equal(1, match.scores[2].Places[2])
+	assert.False(match.isOver)
+
+	match.addGameResult(newTestEndedGame(2, []int{1}, []int{0}))
+	assert.True(match.isOver)
+}
+
+func TestMatchOverByGames(t *testing.T) {
+	match := newMatch(&MatchSettings{Mode: MatchModeGames, Target: 2})
+	match.addGameResult(newTestEndedGame(0, []int{1}, []int{2}))
+	assert.False(t, match.isOver)
+	match.addGameResult(newTestEndedGame(1, []int{0}, []int{2}))
+	assert.True(t, match.isOver)
+}
+
+func TestMatchLeadingPlayerIsPreviousLoser(t *testing.T) {
+	match := newMatch(&MatchSettings{Mode: MatchModeGames, Target: 3})
+	game := newTestEndedGame(1, []int{0}, []int{2})
+	match.addGameResult(game)
+
+	assert.Equal(t, 1, match.getLeadingPlayerIndex(game.players))
+}
+
+func TestMatchScoreboardIsSortedByLosses(t *testing.T) {
+	match := newMatch(&MatchSettings{Mode: MatchModeGames, Target: 3})
+	match.addGameResult(newTestEndedGame(0, []int{1}, []int{2}))
+	match.addGameResult(newTestEndedGame(0, []int{2}, []int{1}))
+	match.addGameResult(newTestEndedGame(2, []int{0}, []int{1}))
+
+	scores := match.toScoreboardEvent().Scores
+	memberIds := make([]uint64, 0)
+	for _, score := range scores {
+		memberIds = append(memberIds, score.MemberId)
+	}
+	assert.Equal(t, []uint64{2, 3, 1}, memberIds)
+}
+
+func TestMatchSettingsValidate(t *testing.T) {
+	settings := &MatchSettings{Mode: "forever", Target: 1}
+	if err := settings.validate(); err == nil {
+		t.Errorf("TestMatchSettingsValidate must be error")
+	}
+}
@@ -1,12 +1,13 @@
 package main
 
-// Player represents connected to a game client which can have cards.
+// Player represents connected to a game client. Cards of the player are in the state of the game.
 type Player struct {
-	Name        string `json:"name"`
-	IsActive    bool   `json:"is_active"`
-	IsCompleted bool
-	client      ClientSender
-	cards       []*Card
+	Name string `json:"name"`
+	// Player has cards or can get them, it is updated from the state of the game before players are sent
+	IsActive  bool   `json:"is_active"`
+	Team      int    `json:"team"`
+	AccountId uint64 `json:"accountId"`
+	client    ClientSender
 }
 
 func (p *Player) sendEvent(event interface{}) {
@@ -19,27 +20,14 @@ func (p *Player) sendMessage(message []byte) {
 
 func newPlayer(client ClientSender, isActive bool) *Player {
 	return &Player{
-		Name:     client.Nickname(),
-		IsActive: isActive,
-		client:   client,
+		Name:      client.Nickname(),
+		IsActive:  isActive,
+		AccountId: client.AccountId(),
+		client:    client,
 	}
 }
 
-func (p *Player) removeCard(card *Card) {
-	cards := make([]*Card, 0)
-	for _, c := range p.cards {
-		if !c.equals(card) {
-			cards = append(cards, c)
-		}
-	}
-	p.cards = cards
-}
-
-func (p *Player) hasCard(card *Card) bool {
-	for _, c := range p.cards {
-		if c.equals(card) {
-			return true
-		}
-	}
-	return false
+func (p *Player) isBot() bool {
+	_, ok := p.client.(*BotClient)
+	return ok
 }
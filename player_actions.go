@@ -12,6 +12,9 @@ const PlayerActionNamePickUp = "pick_up"
 // PlayerActionNameComplete - Complete round
 const PlayerActionNameComplete = "complete"
 
+// PlayerActionNameTransfer - Transfer attack to the next player with card of the same value
+const PlayerActionNameTransfer = "transfer"
+
 // PlayerAction contains command message from a player to a game.
 type PlayerAction struct {
 	Name   string      `json:"name"`
@@ -19,6 +22,15 @@ type PlayerAction struct {
 	player *Player
 }
 
+// PlayerConnection tells the game that connection of the player dropped or was resumed by a new client
+type PlayerConnection struct {
+	client ClientSender
+	// Client with the new connection, nil when the connection dropped
+	newClient ClientSender
+	// Receives whether the client is an active player of the running game
+	isActivePlayer chan bool
+}
+
 // AttackActionData contains data of command message to attack with card from a player to a game.
 type AttackActionData struct {
 	Card *Card `json:"card"`
@@ -29,3 +41,8 @@ type DefendActionData struct {
 	AttackingCard *Card `json:"attackingCard"`
 	DefendingCard *Card `json:"defendingCard"`
 }
+
+// TransferActionData contains data of command message to transfer attack with card from a defender to a game.
+type TransferActionData struct {
+	Card *Card `json:"card"`
+}
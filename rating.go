@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Parameters of Elo rating
+const (
+	InitialRating = 1500
+	RatingKFactor = 32
+)
+
+// PlayerRating contains Elo rating of an account
+type PlayerRating struct {
+	AccountId  uint64    `json:"accountId"`
+	Rating     float64   `json:"rating"`
+	RatedGames int       `json:"ratedGames"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// RatingGameResult contains place of an account in a rated game
+type RatingGameResult struct {
+	AccountId uint64
+	Place     int
+}
+
+// RatingStore keeps ratings of accounts
+type RatingStore interface {
+	// Returns rating of the account or nil if the account has no rated games
+	GetRating(accountId uint64) *PlayerRating
+	// Updates ratings of accounts by places in the game and returns the new ratings
+	AddGameResults(results []*RatingGameResult) (map[uint64]*PlayerRating, error)
+}
+
+// RatingFileStore keeps ratings in a local JSON file
+type RatingFileStore struct {
+	path    string
+	mutex   sync.Mutex
+	ratings map[uint64]*PlayerRating
+}
+
+// NewRatingFileStore loads ratings from the file; the file is created with the first rated game
+func NewRatingFileStore(path string) (*RatingFileStore, error) {
+	s := &RatingFileStore{
+		path:    path,
+		ratings: make(map[uint64]*PlayerRating, 0),
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ratings := make([]*PlayerRating, 0)
+	if err := json.Unmarshal(contents, &ratings); err != nil {
+		return nil, fmt.Errorf("cannot parse ratings file %s: %s", path, err)
+	}
+	for _, r := range ratings {
+		s.ratings[r.AccountId] = r
+	}
+
+	return s, nil
+}
+
+// GetRating returns rating of the account or nil if the account has no rated games
+func (s *RatingFileStore) GetRating(accountId uint64) *PlayerRating {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rating, ok := s.ratings[accountId]
+	if !ok {
+		return nil
+	}
+	ratingCopy := *rating
+	return &ratingCopy
+}
+
+// AddGameResults updates ratings with pairwise Elo changes
+func (s *RatingFileStore) AddGameResults(results []*RatingGameResult) (map[uint64]*PlayerRating, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ratings := make([]float64, len(results))
+	places := make([]int, len(results))
+	for i, result := range results {
+		ratings[i] = InitialRating
+		if rating, ok := s.ratings[result.AccountId]; ok {
+			ratings[i] = rating.Rating
+		}
+		places[i] = result.Place
+	}
+
+	changes := getEloChanges(ratings, places)
+	updatedRatings := make(map[uint64]*PlayerRating, 0)
+	for i, result := range results {
+		rating, ok := s.ratings[result.AccountId]
+		if !ok {
+			rating = &PlayerRating{AccountId: result.AccountId, Rating: InitialRating}
+			s.ratings[result.AccountId] = rating
+		}
+		rating.Rating += changes[i]
+		rating.RatedGames++
+		rating.UpdatedAt = time.Now()
+		ratingCopy := *rating
+		updatedRatings[result.AccountId] = &ratingCopy
+	}
+
+	ratingsList := make([]*PlayerRating, 0)
+	for _, rating := range s.ratings {
+		ratingsList = append(ratingsList, rating)
+	}
+
+	return updatedRatings, writeJsonFile(s.path, ratingsList)
+}
+
+// Returns changes of ratings of players by pairwise comparison of their places:
+// each player wins against players with bigger places and draws with players of the same place.
+// Change is divided by number of opponents, so a game of many players weighs as a game of two.
+func getEloChanges(ratings []float64, places []int) []float64 {
+	changes := make([]float64, len(ratings))
+	opponentsNum := len(ratings) - 1
+	if opponentsNum < 1 {
+		return changes
+	}
+	for i := range ratings {
+		for j := range ratings {
+			if i == j {
+				continue
+			}
+			score := 0.5
+			if places[i] < places[j] {
+				score = 1
+			} else if places[i] > places[j] {
+				score = 0
+			}
+			expectedScore := 1 / (1 + math.Pow(10, (ratings[j]-ratings[i])/400))
+			changes[i] += RatingKFactor * (score - expectedScore) / float64(opponentsNum)
+		}
+	}
+	return changes
+}
+
+// Rating is shown rounded, zero means that player is not rated
+func getRatingToShow(rating *PlayerRating) int {
+	if rating == nil {
+		return 0
+	}
+	return int(math.Round(rating.Rating))
+}
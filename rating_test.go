@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEloChangesOfTwoEqualPlayers(t *testing.T) {
+	changes := getEloChanges([]float64{1500, 1500}, []int{1, 2})
+
+	assert := assert.New(t)
+	assert.InDelta(16, changes[0], 0.001)
+	assert.InDelta(-16, changes[1], 0.001)
+}
+
+func TestEloChangesOfSharedPlace(t *testing.T) {
+	changes := getEloChanges([]float64{1500, 1500, 1500}, []int{1, 1, 3})
+
+	assert := assert.New(t)
+	assert.InDelta(8, changes[0], 0.001)
+	assert.InDelta(8, changes[1], 0.001)
+	assert.InDelta(-16, changes[2], 0.001)
+}
+
+func TestEloChangeOfFavouriteIsSmall(t *testing.T) {
+	changes := getEloChanges([]float64{1900, 1500}, []int{1, 2})
+
+	assert := assert.New(t)
+	assert.True(changes[0] > 0 && changes[0] < 4)
+	assert.InDelta(0, changes[0]+changes[1], 0.001)
+}
+
+func newTestRatingFileStore(t *testing.T) (store *RatingFileStore, path string) {
+	dir, err := ioutil.TempDir("", "durak_ratings")
+	if err != nil {
+		t.Fatalf("Cannot create temp dir: %s", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path = filepath.Join(dir, "ratings.json")
+	store, err = NewRatingFileStore(path)
+	if err != nil {
+		t.Fatalf("Cannot create rating store: %s", err)
+	}
+	return store, path
+}
+
+func TestRatingsSurviveReload(t *testing.T) {
+	store, path := newTestRatingFileStore(t)
+	_, err := store.AddGameResults([]*RatingGameResult{{AccountId: 7, Place: 2}, {AccountId: 9, Place: 1}})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Nil(store.GetRating(8))
+
+	reloadedStore, err := NewRatingFileStore(path)
+	assert.Nil(err)
+	assert.Equal(1484, getRatingToShow(reloadedStore.GetRating(7)))
+	assert.Equal(1516, getRatingToShow(reloadedStore.GetRating(9)))
+	assert.Equal(1, reloadedStore.GetRating(9).RatedGames)
+}
+
+func TestLobbyUpdatesRatingsOfRegisteredPlayers(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	store, _ := newTestRatingFileStore(t)
+	lobby.ratingStore = store
+	clients[0].accountId = 7
+	clients[1].accountId = 9
+	game := room.game
+	game.state.finishingOrder = [][]int{{0}}
+	game.state.loserIndex = 1
+	game.state.endReason = GameEndReasonLoser
+
+	lobby.onGameEnded(game)
+
+	assert := assert.New(t)
+	assert.Equal(1516, clients[0].Rating())
+	assert.Equal(1484, clients[1].Rating())
+	assert.Equal(1484, getTestMemberInfo(room, clients[1]).Rating)
+}
+
+func TestLobbyDoesNotRateGuests(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	store, _ := newTestRatingFileStore(t)
+	lobby.ratingStore = store
+	clients[0].accountId = 7
+	game := room.game
+	game.state.finishingOrder = [][]int{{0}}
+	game.state.loserIndex = 1
+	game.state.endReason = GameEndReasonLoser
+
+	lobby.updateRatings(game)
+
+	assert.Nil(t, store.GetRating(7))
+}
+
+func getTestMemberInfo(room *Room, client *Client) *RoomMemberInfo {
+	for _, m := range room.toRoomInfo().Members {
+		if m.Id == client.Id() {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// GameReplay rebuilds state of a logged game step by step, a step is an entry of the log
+type GameReplay struct {
+	history *GameHistory
+	players []*Player
+	state   *GameState
+	// Index of the last applied entry
+	step int
+	// Defender picked up cards in the current round
+	roundPickUp bool
+}
+
+func newGameReplay(history *GameHistory) *GameReplay {
+	r := &GameReplay{history: history}
+	r.reset()
+	return r
+}
+
+// Creates the state before the first entry
+func (r *GameReplay) reset() {
+	r.players = make([]*Player, 0)
+	for _, hp := range r.history.Players {
+		r.players = append(r.players, &Player{Name: hp.Name, Team: hp.Team, IsActive: !hp.IsLate})
+	}
+	r.state = newGameState(getRulesFromMap(r.history.Rules), r.players)
+	r.step = -1
+	r.roundPickUp = false
+}
+
+func (r *GameReplay) getStepsNum() int {
+	return len(r.history.Entries)
+}
+
+func (r *GameReplay) isEnded() bool {
+	return r.step >= r.getStepsNum()-1
+}
+
+// Rebuilds the game from the beginning up to the given step
+func (r *GameReplay) seek(step int) {
+	if step >= r.getStepsNum() {
+		step = r.getStepsNum() - 1
+	}
+	r.reset()
+	for r.step < step {
+		r.next()
+	}
+}
+
+// Applies the next entry and returns the event which the game sent for it
+func (r *GameReplay) next() interface{} {
+	if r.isEnded() {
+		return nil
+	}
+	r.step++
+	entry := r.history.Entries[r.step]
+	s := r.state
+	wasBattlegroundEmpty := len(s.battleground) == 0
+	r.applyState(entry)
+
+	switch entry.Name {
+	case "Attack":
+		return GameAttackEvent{
+			GameStateInfo: r.getGameStateInfo(),
+			AttackerIndex: r.getActorIndex(entry, s.attackerIndex),
+			DefenderIndex: s.defenderIndex,
+			Card:          getEntryCard(entry, "card"),
+		}
+	case "Defend":
+		return GameDefendEvent{
+			GameStateInfo: r.getGameStateInfo(),
+			DefenderIndex: r.getActorIndex(entry, s.defenderIndex),
+			AttackingCard: getEntryCard(entry, "attackingCard"),
+			DefendingCard: getEntryCard(entry, "defendingCard"),
+		}
+	case "Transfer":
+		return GameTransferEvent{
+			GameStateInfo:    r.getGameStateInfo(),
+			TransferrerIndex: r.getActorIndex(entry, s.attackerIndex),
+			DefenderIndex:    s.defenderIndex,
+			Card:             getEntryCard(entry, "card"),
+		}
+	case "PickUp", "Complete":
+		if entry.Name == "PickUp" {
+			r.roundPickUp = true
+		}
+		if !wasBattlegroundEmpty && len(s.battleground) == 0 {
+			wasAttackSuccessful := r.roundPickUp
+			r.roundPickUp = false
+			return NewRoundEvent{GameStateInfo: r.getGameStateInfo(), WasAttackSuccessful: wasAttackSuccessful}
+		}
+	case "Round ends":
+		r.roundPickUp = false
+		return NewRoundEvent{
+			GameStateInfo:       r.getGameStateInfo(),
+			WasAttackSuccessful: entry.Params["wasAttackSuccessful"] == "true",
+		}
+	case "Player left":
+		playerIndex, _ := strconv.Atoi(entry.Params["player"])
+		return GamePlayerLeftEvent{PlayerIndex: playerIndex, IsAfk: entry.Params["isAfk"] == "true"}
+	case "Game ends":
+		s.isEnded = true
+		placings := make([]*GamePlacing, 0)
+		for _, p := range r.history.Placings {
+			placings = append(placings, &GamePlacing{PlayerIndex: p.PlayerIndex, Place: p.Place})
+		}
+		return GameEndEvent{
+			Reason:     r.history.Reason,
+			HasLoser:   r.history.HasLoser,
+			LoserIndex: r.history.LoserIndex,
+			Placings:   placings,
+		}
+	}
+
+	return GameStateEvent{GameStateInfo: r.getGameStateInfo()}
+}
+
+// Copies the logged state to the state of the game. Text logs have no defending cards,
+// so they are collected from entries of defence during the round.
+func (r *GameReplay) applyState(entry *GameHistoryEntry) {
+	state := entry.State
+	if state == nil {
+		return
+	}
+	s := r.state
+	handsCardsNum := 0
+	for i, cards := range state.PlayersCards {
+		if i < len(s.players) {
+			s.players[i].cards = cards
+			s.players[i].isActive = len(cards) > 0 || len(state.Deck) > 0
+			handsCardsNum += len(cards)
+		}
+	}
+	s.deck = &Deck{cards: state.Deck}
+	if s.deck.cards == nil {
+		s.deck.cards = make([]*Card, 0)
+	}
+	if len(state.Battleground) < len(s.battleground) {
+		s.defendingCards = make(map[int]*Card, 0)
+	}
+	s.battleground = state.Battleground
+	if s.battleground == nil {
+		s.battleground = make([]*Card, 0)
+	}
+	if state.DefendingCards != nil {
+		s.defendingCards = make(map[int]*Card, 0)
+		for i, card := range state.DefendingCards {
+			if card != nil {
+				s.defendingCards[i] = card
+			}
+		}
+	} else if entry.Name == "Defend" {
+		attackingCard := getEntryCard(entry, "attackingCard")
+		for i, card := range s.battleground {
+			if attackingCard != nil && card.equals(attackingCard) {
+				s.defendingCards[i] = getEntryCard(entry, "defendingCard")
+			}
+		}
+	}
+	s.attackerIndex = state.AttackerIndex
+	s.defenderIndex = state.DefenderIndex
+	s.trumpCard = state.Trump
+	if s.trumpCard != nil {
+		s.trumpSuit = s.trumpCard.Suit
+	}
+	s.defenderPickUp = entry.Name == "PickUp" || (s.defenderPickUp && len(s.battleground) > 0)
+
+	// Discard pile is not logged in text logs, so it is counted from other cards
+	s.discardPileSize = s.rules.DeckSize - len(s.deck.cards) - handsCardsNum - len(s.battleground) - len(s.defendingCards)
+	if s.discardPileSize < 0 {
+		s.discardPileSize = 0
+	}
+}
+
+// Replays show state for a spectator with hands of all players
+func (r *GameReplay) getGameStateInfo() *GameStateInfo {
+	gsi := r.state.getGameStateInfo(-1)
+	gsi.TurnPlayerIndex = -1
+	gsi.Hands = make([][]*Card, 0)
+	for i, p := range r.state.players {
+		gsi.Hands = append(gsi.Hands, p.cards)
+		gsi.TimeBanksSeconds[i] = r.state.rules.TimeBankSeconds
+	}
+	return gsi
+}
+
+func (r *GameReplay) getActorIndex(entry *GameHistoryEntry, defaultIndex int) int {
+	if entry.ActorIndex != nil {
+		return *entry.ActorIndex
+	}
+	return defaultIndex
+}
+
+// Returns card of the action from data of JSON logs or from parameters of text logs
+func getEntryCard(entry *GameHistoryEntry, name string) *Card {
+	if entry.Action != nil {
+		cards := make(map[string]*Card, 0)
+		if err := json.Unmarshal(entry.Action, &cards); err == nil {
+			return cards[name]
+		}
+	}
+	cards := parseCardsString(entry.Params[name])
+	if len(cards) == 0 {
+		return nil
+	}
+	return cards[0]
+}
+
+// Restores rules from the log, unknown values are left default
+func getRulesFromMap(rulesMap map[string]string) *GameRules {
+	rules := newGameRules()
+	getInt := func(key string, value *int) {
+		if v, err := strconv.Atoi(rulesMap[key]); err == nil {
+			*value = v
+		}
+	}
+	getBool := func(key string, value *bool) {
+		if v, err := strconv.ParseBool(rulesMap[key]); err == nil {
+			*value = v
+		}
+	}
+	getInt("deckSize", &rules.DeckSize)
+	getInt("handSize", &rules.HandSize)
+	getInt("firstRoundAttackLimit", &rules.FirstRoundAttackLimit)
+	getInt("maxCardsPerBout", &rules.MaxCardsPerBout)
+	getBool("transfer", &rules.Transfer)
+	getBool("teamPlay", &rules.TeamPlay)
+	getInt("moveSeconds", &rules.MoveSeconds)
+	getInt("timeBankSeconds", &rules.TimeBankSeconds)
+	if throwIn, ok := rulesMap["throwIn"]; ok {
+		rules.ThrowIn = throwIn
+	}
+	return rules
+}
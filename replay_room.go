@@ -0,0 +1,142 @@
+package main
+
+import (
+	"time"
+)
+
+// Timing of replays
+const (
+	ReplayStepDuration = time.Second
+	ReplayMinSpeed     = 0.25
+	ReplayMaxSpeed     = 8
+)
+
+// ReplayRoom plays a logged game back to one client with the same events as a running game
+type ReplayRoom struct {
+	client    *Client
+	replay    *GameReplay
+	isPlaying bool
+	speed     float64
+	controls  chan *ReplayControlCommandData
+	// Closed to stop the replay
+	done chan struct{}
+	// Closed when the replay is stopped and does not send to the client anymore
+	stopped chan struct{}
+}
+
+func newReplayRoom(client *Client, replay *GameReplay) *ReplayRoom {
+	return &ReplayRoom{
+		client:   client,
+		replay:   replay,
+		speed:    1,
+		controls: make(chan *ReplayControlCommandData, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Sends the beginning of the game and plays it until the room is closed
+func (rr *ReplayRoom) run() {
+	defer close(rr.stopped)
+	history := rr.replay.history
+	rr.sendEvent(&ReplayOpenedEvent{
+		GameId:   history.Id,
+		StepsNum: rr.replay.getStepsNum(),
+	})
+	rr.sendEvent(GamePlayersEvent{YourPlayerIndex: -1, Players: rr.replay.players})
+	rr.step()
+	rr.sendEvent(GameStartedEvent{
+		GameStateInfo: rr.replay.getGameStateInfo(),
+		GameRules:     rr.replay.state.rules,
+		DeckHash:      history.DeckHash,
+	})
+	rr.isPlaying = true
+	rr.sendState()
+
+	var nextStep <-chan time.Time
+	for {
+		if rr.isPlaying && nextStep == nil {
+			nextStep = time.After(time.Duration(float64(ReplayStepDuration) / rr.speed))
+		} else if !rr.isPlaying {
+			nextStep = nil
+		}
+
+		select {
+		case <-rr.done:
+			return
+		case data := <-rr.controls:
+			rr.onControl(data)
+			nextStep = nil
+		case <-nextStep:
+			nextStep = nil
+			rr.step()
+			if rr.replay.isEnded() {
+				rr.isPlaying = false
+				rr.sendState()
+			}
+		}
+	}
+}
+
+func (rr *ReplayRoom) step() {
+	event := rr.replay.next()
+	if event != nil {
+		rr.sendEvent(event)
+	}
+}
+
+func (rr *ReplayRoom) onControl(data *ReplayControlCommandData) {
+	if data.Speed != nil {
+		speed := *data.Speed
+		if speed < ReplayMinSpeed {
+			speed = ReplayMinSpeed
+		} else if speed > ReplayMaxSpeed {
+			speed = ReplayMaxSpeed
+		}
+		rr.speed = speed
+	}
+	if data.Step != nil && *data.Step >= 0 {
+		rr.replay.seek(*data.Step)
+		rr.sendEvent(GameStateEvent{GameStateInfo: rr.replay.getGameStateInfo()})
+	}
+	if data.IsPlaying != nil {
+		rr.isPlaying = *data.IsPlaying
+		if rr.isPlaying && rr.replay.isEnded() {
+			rr.replay.seek(0)
+			rr.sendEvent(GameStateEvent{GameStateInfo: rr.replay.getGameStateInfo()})
+		}
+	}
+	rr.sendState()
+}
+
+func (rr *ReplayRoom) sendState() {
+	rr.sendEvent(&ReplayStateEvent{
+		Step:      rr.replay.step,
+		StepsNum:  rr.replay.getStepsNum(),
+		IsPlaying: rr.isPlaying,
+		Speed:     rr.speed,
+	})
+}
+
+// Sends the event to the client unless the replay is stopped, the client can be disconnected and not read events
+func (rr *ReplayRoom) sendEvent(event interface{}) {
+	jsonData, _ := eventToJSON(event)
+	select {
+	case rr.client.send <- jsonData:
+	case <-rr.done:
+	}
+}
+
+// Passes the control to the replay, the control is dropped if the previous one is not handled yet
+func (rr *ReplayRoom) control(data *ReplayControlCommandData) {
+	select {
+	case rr.controls <- data:
+	default:
+	}
+}
+
+// Stops the replay and waits until it does not send to the client, so the send channel of the client can be closed
+func (rr *ReplayRoom) close() {
+	close(rr.done)
+	<-rr.stopped
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameReplay(t *testing.T) {
+	for _, format := range []string{GameLogFormatText, GameLogFormatJson} {
+		dir := newTestGameLogDir(t)
+		var logger BufferedGameLogger = newTestGameFileLogger(t, dir)
+		if format == GameLogFormatJson {
+			logger = NewGameJsonLogger(dir, func(err error) {
+				t.Errorf("Cannot write log: %s", err)
+			})
+		}
+		game := writeTestGameLog(t, logger)
+		history, err := readGameHistory(dir, game.id)
+		if err != nil {
+			t.Fatalf("Cannot read game: %s", err)
+		}
+
+		replay := newGameReplay(history)
+		assert := assert.New(t)
+		assert.Equal(4, replay.getStepsNum(), format)
+		assert.IsType(GameStateEvent{}, replay.next(), format)
+
+		attackEvent, ok := replay.next().(GameAttackEvent)
+		assert.True(ok, format)
+		assert.Equal(0, attackEvent.AttackerIndex, format)
+		assert.Equal(1, attackEvent.DefenderIndex, format)
+		assert.Equal(&Card{"7", "♥"}, attackEvent.Card, format)
+
+		defendEvent, ok := replay.next().(GameDefendEvent)
+		assert.True(ok, format)
+		assert.Equal(1, defendEvent.DefenderIndex, format)
+		assert.Equal(&Card{"10", "♠"}, defendEvent.DefendingCard, format)
+		assert.Equal(map[int]*Card{0: {"10", "♠"}}, defendEvent.GameStateInfo.DefendingCards, format)
+		assert.Equal([][]*Card{{}, {{"8", "♣"}}}, defendEvent.GameStateInfo.Hands, format)
+		assert.Equal(1, defendEvent.GameStateInfo.DeckSize, format)
+		assert.Equal(32, defendEvent.GameStateInfo.DiscardPileSize, format)
+
+		endEvent, ok := replay.next().(GameEndEvent)
+		assert.True(ok, format)
+		assert.True(endEvent.HasLoser, format)
+		assert.Equal(1, endEvent.LoserIndex, format)
+		assert.True(replay.isEnded(), format)
+		assert.Nil(replay.next(), format)
+
+		replay.seek(1)
+		assert.Equal(1, replay.step, format)
+		assert.Equal([]*Card{{"7", "♥"}}, replay.state.battleground, format)
+		assert.Empty(replay.state.defendingCards, format)
+		assert.Equal([]*Card{{"10", "♠"}, {"8", "♣"}}, replay.state.players[1].cards, format)
+	}
+}
+
+func TestGetRulesFromMap(t *testing.T) {
+	rules := getRulesFromMap(map[string]string{
+		"deckSize":    "52",
+		"transfer":    "true",
+		"throwIn":     "neighbors",
+		"handSize":    "bad",
+		"moveSeconds": "20",
+	})
+
+	expected := newGameRules()
+	expected.DeckSize = 52
+	expected.Transfer = true
+	expected.ThrowIn = "neighbors"
+	expected.MoveSeconds = 20
+	assert.Equal(t, expected, rules)
+}
+
+func TestReplayRoomClosesWhenClientDoesNotRead(t *testing.T) {
+	dir := newTestGameLogDir(t)
+	game := writeTestGameLog(t, newTestGameFileLogger(t, dir))
+	history, err := readGameHistory(dir, game.id)
+	if err != nil {
+		t.Fatalf("Cannot read game: %s", err)
+	}
+
+	client := &Client{id: 1, isValid: true, send: make(chan []byte, 1)}
+	replayRoom := newReplayRoom(client, newGameReplay(history))
+	go replayRoom.run()
+	playing := false
+	replayRoom.control(&ReplayControlCommandData{IsPlaying: &playing})
+	replayRoom.control(&ReplayControlCommandData{IsPlaying: &playing})
+	replayRoom.close()
+
+	close(client.send)
+}
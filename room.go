@@ -2,12 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"sync/atomic"
+	"time"
 )
 
-// MaxPlayersInRoom limits maximum number of players in room
-const MaxPlayersInRoom = 6
+// MaxPlayersInRoom limits maximum number of players in room with the biggest deck
+const MaxPlayersInRoom = 8
 
 // RoomMember represents connected to a room client.
 type RoomMember struct {
@@ -15,6 +17,7 @@ type RoomMember struct {
 	wantToPlay bool
 	isPlayer   bool
 	isBot      bool
+	team       int
 }
 
 // Room represents place where some of members want to start a new game.
@@ -24,6 +27,8 @@ type Room struct {
 	members map[*RoomMember]bool
 	game    *Game
 	lobby   *Lobby
+	rules   *GameRules
+	match   *Match
 }
 
 func newRoom(roomId uint64, owner *Client, lobby *Lobby) *Room {
@@ -31,14 +36,14 @@ func newRoom(roomId uint64, owner *Client, lobby *Lobby) *Room {
 	ownerInRoom := newRoomMember(owner, false)
 	ownerInRoom.isPlayer = true
 	members[ownerInRoom] = true
-	room := &Room{roomId, ownerInRoom, members, nil, lobby}
+	room := &Room{roomId, ownerInRoom, members, nil, lobby, newGameRules(), nil}
 	owner.room = room
 
 	return room
 }
 
 func newRoomMember(client ClientSender, isBot bool) *RoomMember {
-	return &RoomMember{client, true, false, isBot}
+	return &RoomMember{client, true, false, isBot, TeamNone}
 }
 
 // Name returns name of the room by its owner.
@@ -68,7 +73,7 @@ func (r *Room) removeClient(client ClientSender) (changedOwner bool, roomBecameE
 	delete(r.members, member)
 
 	if r.game != nil {
-		r.game.onClientRemoved(client)
+		r.game.removeClient(client)
 	}
 
 	roomUpdatedEvent := &RoomUpdatedEvent{r.toRoomInfo()}
@@ -95,6 +100,18 @@ func (r *Room) removeClient(client ClientSender) (changedOwner bool, roomBecameE
 	return
 }
 
+// Moves membership of the client whose connection dropped to the client with a new connection.
+// The seat in game is moved by the game.
+func (r *Room) replaceClient(oldClient *Client, newClient *Client) {
+	newClient.room = r
+	oldClient.room = nil
+	for member := range r.members {
+		if member.client == oldClient {
+			member.client = newClient
+		}
+	}
+}
+
 func (r *Room) addClient(client *Client) {
 	member := newRoomMember(client, false)
 	r.members[member] = true
@@ -110,7 +127,11 @@ func (r *Room) addClient(client *Client) {
 	roomJoinedEvent := RoomJoinedEvent{r.toRoomInfo()}
 	client.sendEvent(roomJoinedEvent)
 
-	if r.game != nil && r.game.status == GameStatusPlaying {
+	if r.match != nil {
+		client.sendEvent(r.match.toScoreboardEvent())
+	}
+
+	if r.game != nil && r.game.getStatus() == GameStatusPlaying {
 		player := newPlayer(client, false)
 		r.game.players = append(r.game.players, player)
 		r.game.onLatePlayerJoin(player)
@@ -165,7 +186,7 @@ func (r *Room) hasSlotForPlayer() bool {
 			membersWhoWantToPlayNum++
 		}
 	}
-	return membersWhoWantToPlayNum+1 <= MaxPlayersInRoom
+	return membersWhoWantToPlayNum+1 <= r.rules.getMaxPlayers()
 }
 
 func (r *Room) changeMemberWantStatus(client *Client, wantToPlay bool) {
@@ -238,39 +259,192 @@ func (r *Room) setPlayerStatus(memberId uint64, playerStatus bool) {
 	r.broadcastEvent(roomMemberChangedPlayerStatusEvent, nil)
 }
 
-func (r *Room) onStartGameCommand(c *Client) {
-	pls := r.getPlayers()
-	if len(pls) < 2 {
-		errEvent := &ClientCommandError{errorNeedOneMorePlayer}
+func (r *Room) onStartGameCommand(c *Client, seed *int64) {
+	if seed != nil && !r.lobby.allowGameSeed {
+		errEvent := &ClientCommandError{errorGameSeedIsNotAllowed}
 		c.sendEvent(errEvent)
 		return
 	}
-	if len(pls) > MaxPlayersInRoom {
-		errEvent := &ClientCommandError{errorNumberOfPlayersExceededLimit}
+	if err := r.startGame(seed); err != nil {
+		errEvent := &ClientCommandError{err.Error()}
 		c.sendEvent(errEvent)
-		return
+	}
+}
+
+// Starts a new game with members who are players. Returned error contains message for client.
+// Not nil seed is used to shuffle the deck instead of a random one.
+func (r *Room) startGame(seed *int64) error {
+	pls := r.getPlayers()
+	if len(pls) < 2 {
+		return errors.New(errorNeedOneMorePlayer)
+	}
+	if len(pls) > r.rules.getMaxPlayers() {
+		return errors.New(errorNumberOfPlayersExceededLimit)
 	}
 	if r.game != nil {
-		errEvent := &ClientCommandError{errorGameHasBeenAlreadyStarted}
-		c.sendEvent(errEvent)
-		return
+		return errors.New(errorGameHasBeenAlreadyStarted)
 	}
 
 	players := make([]*Player, 0)
-	for rm := range r.members {
-		if rm.isPlayer {
+	if r.rules.TeamPlay {
+		seatedMembers, ok := r.getTeamSeating(pls)
+		if !ok {
+			return errors.New(errorTeamsAreNotEqual)
+		}
+		for _, rm := range seatedMembers {
 			player := newPlayer(rm.client, rm.isPlayer)
+			player.Team = rm.team
 			players = append(players, player)
 		}
+	} else {
+		for rm := range r.members {
+			if rm.isPlayer {
+				player := newPlayer(rm.client, rm.isPlayer)
+				players = append(players, player)
+			}
+		}
 	}
 
-	r.game = newGame(r, players, r.lobby.gameLogger)
+	r.game = newGame(r, players, r.lobby.gameLogger, r.rules)
+	if r.match != nil {
+		r.game.leadingPlayerIndex = r.match.getLeadingPlayerIndex(players)
+	}
+	if seed != nil {
+		r.game.setSeed(*seed)
+	}
 	go r.game.begin()
 
 	roomUpdatedEvent := &RoomUpdatedEvent{r.toRoomInfo()}
 	r.broadcastEvent(roomUpdatedEvent, nil)
 
 	r.lobby.sendRoomUpdate(r)
+
+	return nil
+}
+
+func (r *Room) onSetMatchCommand(c *Client, settings *MatchSettings) {
+	if r.owner.client.Id() != c.Id() {
+		errEvent := &ClientCommandError{errorYouShouldBeOwner}
+		c.sendEvent(errEvent)
+		return
+	}
+	if r.isGamePlaying() {
+		errEvent := &ClientCommandError{errorGameHasBeenAlreadyStarted}
+		c.sendEvent(errEvent)
+		return
+	}
+
+	if settings.Target == 0 {
+		// Zero target turns match mode off
+		r.match = nil
+	} else {
+		if err := settings.validate(); err != nil {
+			log.Printf("Invalid match settings: %s", err)
+			errEvent := &ClientCommandError{errorInvalidMatchSettings}
+			c.sendEvent(errEvent)
+			return
+		}
+		r.match = newMatch(settings)
+		r.broadcastEvent(r.match.toScoreboardEvent(), nil)
+	}
+
+	roomUpdatedEvent := &RoomUpdatedEvent{r.toRoomInfo()}
+	r.broadcastEvent(roomUpdatedEvent, nil)
+}
+
+// Checks if the game of the room is running. Settings can be changed for the next game when the previous one is over.
+func (r *Room) isGamePlaying() bool {
+	return r.game != nil && r.game.getStatus() != GameStatusEnd
+}
+
+// Starts the next game of the match when the previous one is over
+func (r *Room) startNextMatchGame() {
+	if r.match == nil || r.match.isOver {
+		return
+	}
+	if r.isGamePlaying() {
+		return
+	}
+
+	r.game = nil
+	if err := r.startGame(nil); err != nil {
+		log.Printf("Cannot start next game of match: %s", err)
+		r.match.isOver = true
+		r.broadcastEvent(r.match.toScoreboardEvent(), nil)
+		errEvent := &ClientCommandError{err.Error()}
+		r.owner.client.sendEvent(errEvent)
+	}
+}
+
+// Puts players of two equal teams in turn, so partners sit opposite each other.
+// Players without a team join the smaller team.
+func (r *Room) getTeamSeating(players []*RoomMember) (seatedMembers []*RoomMember, ok bool) {
+	teams := map[int][]*RoomMember{TeamFirst: {}, TeamSecond: {}}
+	noTeamMembers := make([]*RoomMember, 0)
+	for _, rm := range players {
+		if rm.team == TeamNone {
+			noTeamMembers = append(noTeamMembers, rm)
+			continue
+		}
+		teams[rm.team] = append(teams[rm.team], rm)
+	}
+	for _, rm := range noTeamMembers {
+		team := TeamFirst
+		if len(teams[TeamSecond]) < len(teams[TeamFirst]) {
+			team = TeamSecond
+		}
+		teams[team] = append(teams[team], rm)
+	}
+
+	if len(teams[TeamFirst]) < 2 || len(teams[TeamFirst]) != len(teams[TeamSecond]) {
+		return nil, false
+	}
+
+	for team, members := range teams {
+		for _, rm := range members {
+			rm.team = team
+		}
+	}
+	for i := range teams[TeamFirst] {
+		seatedMembers = append(seatedMembers, teams[TeamFirst][i], teams[TeamSecond][i])
+	}
+
+	return seatedMembers, true
+}
+
+func (r *Room) onSetMemberTeamCommand(c *Client, memberId uint64, team int) {
+	if r.owner.client.Id() != c.Id() {
+		errEvent := &ClientCommandError{errorYouShouldBeOwner}
+		c.sendEvent(errEvent)
+		return
+	}
+	if r.isGamePlaying() {
+		errEvent := &ClientCommandError{errorCantChangeStatusGameHasBeenStarted}
+		c.sendEvent(errEvent)
+		return
+	}
+	if team != TeamNone && team != TeamFirst && team != TeamSecond {
+		errEvent := &ClientCommandError{errorInvalidTeam}
+		c.sendEvent(errEvent)
+		return
+	}
+
+	var foundMember *RoomMember
+	for rm := range r.members {
+		if rm.client.Id() == memberId {
+			rm.team = team
+			foundMember = rm
+			break
+		}
+	}
+
+	if foundMember == nil {
+		return
+	}
+
+	memberInfo := foundMember.memberToRoomMemberInfo()
+	roomMemberChangedStatusEvent := &RoomMemberChangedStatusEvent{memberInfo}
+	r.broadcastEvent(roomMemberChangedStatusEvent, nil)
 }
 
 func (r *Room) onDeleteGameCommand(c *Client) {
@@ -285,6 +459,9 @@ func (r *Room) onDeleteGameCommand(c *Client) {
 		return
 	}
 
+	// The entry goes after the end of the game which is logged by its loop
+	r.game.delete()
+	r.game.gameLogger.LogGameDeleted(r.game)
 	r.game = nil
 
 	roomUpdatedEvent := &RoomUpdatedEvent{r.toRoomInfo()}
@@ -293,7 +470,31 @@ func (r *Room) onDeleteGameCommand(c *Client) {
 	r.lobby.sendRoomUpdate(r)
 }
 
-func (r *Room) onAddBotCommand(c *Client) {
+func (r *Room) onSetGameRulesCommand(c *Client, rules *GameRules) {
+	if r.owner.client.Id() != c.Id() {
+		errEvent := &ClientCommandError{errorYouShouldBeOwner}
+		c.sendEvent(errEvent)
+		return
+	}
+	if r.isGamePlaying() {
+		errEvent := &ClientCommandError{errorGameHasBeenAlreadyStarted}
+		c.sendEvent(errEvent)
+		return
+	}
+	if err := rules.validate(); err != nil {
+		log.Printf("Invalid game rules: %s", err)
+		errEvent := &ClientCommandError{errorInvalidGameRules}
+		c.sendEvent(errEvent)
+		return
+	}
+
+	r.rules = rules
+
+	roomUpdatedEvent := &RoomUpdatedEvent{r.toRoomInfo()}
+	r.broadcastEvent(roomUpdatedEvent, nil)
+}
+
+func (r *Room) onAddBotCommand(c *Client, level string) {
 	if r.owner.client.Id() != c.Id() {
 		errEvent := &ClientCommandError{errorYouShouldBeOwner}
 		c.sendEvent(errEvent)
@@ -309,10 +510,18 @@ func (r *Room) onAddBotCommand(c *Client) {
 		c.sendEvent(errEvent)
 		return
 	}
+	if level == "" {
+		level = BotLevelNormal
+	}
+	if !isValidBotLevel(level) {
+		errEvent := &ClientCommandError{errorInvalidBotLevel}
+		c.sendEvent(errEvent)
+		return
+	}
 
 	atomic.AddUint64(&lastClientId, 1)
 	lastBotIdSafe := atomic.LoadUint64(&lastClientId)
-	bot := newBotClient(lastBotIdSafe, r)
+	bot := newBotClient(lastBotIdSafe, r, level)
 	r.addBot(bot)
 }
 
@@ -349,11 +558,42 @@ func (r *Room) onClientCommand(cc *ClientCommand) {
 		}
 		r.onSetPlayerStatusCommand(cc.client, statusData.MemberId, statusData.Status)
 	case ClientCommandRoomSubTypeStartGame:
-		r.onStartGameCommand(cc.client)
+		var startData RoomStartGameCommandData
+		if len(cc.Data) > 0 {
+			if err := json.Unmarshal(cc.Data, &startData); err != nil {
+				return
+			}
+		}
+		r.onStartGameCommand(cc.client, startData.Seed)
 	case ClientCommandRoomSubTypeDeleteGame:
 		r.onDeleteGameCommand(cc.client)
+	case ClientCommandRoomSubTypeSetGameRules:
+		// Fields which are not sent keep current values
+		rules := *r.rules
+		if err := json.Unmarshal(cc.Data, &rules); err != nil {
+			return
+		}
+		r.onSetGameRulesCommand(cc.client, &rules)
+	case ClientCommandRoomSubTypeSetMemberTeam:
+		var teamData RoomSetMemberTeamCommandData
+		if err := json.Unmarshal(cc.Data, &teamData); err != nil {
+			return
+		}
+		r.onSetMemberTeamCommand(cc.client, teamData.MemberId, teamData.Team)
+	case ClientCommandRoomSubTypeSetMatch:
+		var settings MatchSettings
+		if err := json.Unmarshal(cc.Data, &settings); err != nil {
+			return
+		}
+		r.onSetMatchCommand(cc.client, &settings)
 	case ClientCommandRoomSubTypeAddBot:
-		r.onAddBotCommand(cc.client)
+		var botData RoomAddBotCommandData
+		if len(cc.Data) > 0 {
+			if err := json.Unmarshal(cc.Data, &botData); err != nil {
+				return
+			}
+		}
+		r.onAddBotCommand(cc.client, botData.Level)
 	case ClientCommandRoomSubTypeRemoveBots:
 		r.onRemoveBotsCommand(cc.client)
 	}
@@ -365,26 +605,43 @@ func (r *Room) onGameStarted() {
 	r.lobby.sendRoomUpdate(r)
 }
 
-func (r *Room) onGameEnded() {
+func (r *Room) onGameEnded(game *Game) {
 	roomUpdatedEvent := &RoomUpdatedEvent{r.toRoomInfo()}
 	r.broadcastEvent(roomUpdatedEvent, nil)
 	r.lobby.sendRoomUpdate(r)
+
+	if r.match != nil && !r.match.isOver && game.state.endReason != GameEndReasonDeleted {
+		r.match.addGameResult(game)
+		r.broadcastEvent(r.match.toScoreboardEvent(), nil)
+		if !r.match.isOver {
+			// Rooms are changed by the lobby only
+			time.AfterFunc(time.Second*MatchNextGameDelaySeconds, func() {
+				r.lobby.matchNextGames <- r
+			})
+		}
+	}
 }
 
 func (rm *RoomMember) memberToRoomMemberInfo() *RoomMemberInfo {
-	return &RoomMemberInfo{
+	memberInfo := &RoomMemberInfo{
 		Id:         rm.client.Id(),
 		Nickname:   rm.client.Nickname(),
 		WantToPlay: rm.wantToPlay,
 		IsPlayer:   rm.isPlayer,
 		IsBot:      rm.isBot,
+		Team:       rm.team,
+		Rating:     rm.client.Rating(),
 	}
+	if botClient, ok := rm.client.(*BotClient); ok {
+		memberInfo.BotLevel = botClient.level
+	}
+	return memberInfo
 }
 
 func (r *Room) toRoomInList() *RoomInList {
 	gameStatus := ""
 	if r.game != nil {
-		gameStatus = r.game.status
+		gameStatus = r.game.getStatus()
 	}
 	roomInList := &RoomInList{
 		Id:         r.Id(),
@@ -399,7 +656,7 @@ func (r *Room) toRoomInList() *RoomInList {
 func (r *Room) toRoomInfo() *RoomInfo {
 	gameStatus := ""
 	if r.game != nil {
-		gameStatus = r.game.status
+		gameStatus = r.game.getStatus()
 	}
 
 	membersInfo := make([]*RoomMemberInfo, 0)
@@ -414,7 +671,11 @@ func (r *Room) toRoomInfo() *RoomInfo {
 		Name:       r.Name(),
 		GameStatus: gameStatus,
 		Members:    membersInfo,
-		MaxPlayers: MaxPlayersInRoom,
+		MaxPlayers: r.rules.getMaxPlayers(),
+		GameRules:  r.rules,
+	}
+	if r.match != nil {
+		roomInfo.Match = r.match.settings
 	}
 	return roomInfo
 }
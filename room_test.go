@@ -115,3 +115,79 @@ func TestRemoveRegularClient(t *testing.T) {
 		t.Errorf("TestRemoveRegularClient expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestTeamSeating(t *testing.T) {
+	client := &Client{id: 1, nickname: "test_nickname"}
+	room := newRoom(1, client, nil)
+	members := []*RoomMember{
+		{client: client, team: TeamFirst},
+		{client: &Client{id: 2}, team: TeamFirst},
+		{client: &Client{id: 3}, team: TeamSecond},
+		{client: &Client{id: 4}},
+	}
+	seatedMembers, ok := room.getTeamSeating(members)
+	if !ok {
+		t.Fatalf("TestTeamSeating expected equal teams")
+	}
+	for i, rm := range seatedMembers {
+		expected := TeamFirst + i%2
+		if rm.team != expected {
+			t.Errorf("TestTeamSeating expected team %v at seat %v, got: %v", expected, i, rm.team)
+		}
+	}
+}
+
+func TestTeamSeatingNotEqualTeams(t *testing.T) {
+	client := &Client{id: 1, nickname: "test_nickname"}
+	room := newRoom(1, client, nil)
+	members := []*RoomMember{
+		{client: client, team: TeamFirst},
+		{client: &Client{id: 2}, team: TeamFirst},
+		{client: &Client{id: 3}, team: TeamFirst},
+		{client: &Client{id: 4}},
+	}
+	_, ok := room.getTeamSeating(members)
+	if ok {
+		t.Errorf("TestTeamSeatingNotEqualTeams expected not equal teams")
+	}
+}
+
+func TestAddBotWithLevel(t *testing.T) {
+	client := &Client{id: 123, nickname: "test_nickname", send: make(chan []byte, 10), isValid: true}
+	room := newRoom(1, client, nil)
+	room.onAddBotCommand(client, BotLevelHard)
+	<-client.send
+	room.onAddBotCommand(client, "impossible")
+
+	botLevels := make([]string, 0)
+	for _, memberInfo := range room.toRoomInfo().Members {
+		if memberInfo.IsBot {
+			botLevels = append(botLevels, memberInfo.BotLevel)
+		}
+	}
+	if len(botLevels) != 1 || botLevels[0] != BotLevelHard {
+		t.Errorf("TestAddBotWithLevel expected one hard bot, got: %v", botLevels)
+	}
+	errorMessage := string(<-client.send)
+	expected := `{"name":"ClientCommandError","data":{"message":"invalid_bot_level"}}`
+	if errorMessage != expected {
+		t.Errorf("TestAddBotWithLevel expected: %v, got: %v", expected, errorMessage)
+	}
+}
+
+func TestSetGameRulesKeepsNotSentFields(t *testing.T) {
+	client := &Client{id: 123, nickname: "test_nickname", send: make(chan []byte, 10), isValid: true}
+	room := newRoom(1, client, nil)
+	room.game = &Game{status: GameStatusEnd}
+	room.onClientCommand(&ClientCommand{
+		SubType: ClientCommandRoomSubTypeSetGameRules,
+		Data:    []byte(`{"transfer":true}`),
+		client:  client,
+	})
+
+	expected := newGameRules()
+	expected.Transfer = true
+	if *room.rules != *expected {
+		t.Errorf("TestSetGameRulesKeepsNotSentFields expected: %+v, got: %+v", expected, room.rules)
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// LobbySnapshot contains state of the server which is saved on shutdown and restored at startup.
+type LobbySnapshot struct {
+	LastClientId uint64            `json:"lastClientId"`
+	LastRoomId   uint64            `json:"lastRoomId"`
+	Clients      []*ClientSnapshot `json:"clients"`
+	Rooms        []*RoomSnapshot   `json:"rooms"`
+}
+
+// ClientSnapshot contains identity of a connected client who can resume his session after restart
+type ClientSnapshot struct {
+	Id           uint64 `json:"id"`
+	Nickname     string `json:"nickname"`
+	AccountId    uint64 `json:"accountId"`
+	SessionToken string `json:"sessionToken"`
+}
+
+// RoomSnapshot contains state of a room with its members and the running game
+type RoomSnapshot struct {
+	Id      uint64                `json:"id"`
+	OwnerId uint64                `json:"ownerId"`
+	Members []*RoomMemberSnapshot `json:"members"`
+	Rules   *GameRules            `json:"rules"`
+	Match   *MatchSnapshot        `json:"match"`
+	Game    *GameSnapshot         `json:"game"`
+}
+
+// RoomMemberSnapshot contains state of a member of room, bots are recreated with the same id and nickname
+type RoomMemberSnapshot struct {
+	ClientId   uint64 `json:"clientId"`
+	Nickname   string `json:"nickname"`
+	WantToPlay bool   `json:"wantToPlay"`
+	IsPlayer   bool   `json:"isPlayer"`
+	IsBot      bool   `json:"isBot"`
+	Team       int    `json:"team"`
+	BotLevel   string `json:"botLevel,omitempty"`
+}
+
+// MatchSnapshot contains cumulative scores of the match in room
+type MatchSnapshot struct {
+	Settings     *MatchSettings `json:"settings"`
+	Scores       []*MatchScore  `json:"scores"`
+	GamesPlayed  int            `json:"gamesPlayed"`
+	LastLoserIds []uint64       `json:"lastLoserIds"`
+	IsOver       bool           `json:"isOver"`
+}
+
+// GameSnapshot contains state of the running game
+type GameSnapshot struct {
+	Id                            string            `json:"id"`
+	Players                       []*PlayerSnapshot `json:"players"`
+	Deck                          []*Card           `json:"deck"`
+	DiscardPileSize               int               `json:"discardPileSize"`
+	TrumpCard                     *Card             `json:"trumpCard"`
+	TrumpCardIsOwnedByPlayerIndex int               `json:"trumpCardIsOwnedByPlayerIndex"`
+	AttackerIndex                 int               `json:"attackerIndex"`
+	DefenderIndex                 int               `json:"defenderIndex"`
+	Battleground                  []*Card           `json:"battleground"`
+	DefendingCards                map[int]*Card     `json:"defendingCards"`
+	DefenderPickUp                bool              `json:"defenderPickUp"`
+	RoundsPlayed                  int               `json:"roundsPlayed"`
+	FinishingOrder                [][]int           `json:"finishingOrder"`
+	Rules                         *GameRules        `json:"rules"`
+	Seed                          int64             `json:"seed"`
+	DeckOrder                     string            `json:"deckOrder"`
+	DeckSalt                      string            `json:"deckSalt"`
+	DeckHash                      string            `json:"deckHash"`
+	TimeBanksSeconds              []int             `json:"timeBanksSeconds"`
+	StartedAt                     time.Time         `json:"startedAt"`
+}
+
+// PlayerSnapshot contains state of a player in game
+type PlayerSnapshot struct {
+	ClientId    uint64          `json:"clientId"`
+	IsActive    bool            `json:"isActive"`
+	IsCompleted bool            `json:"isCompleted"`
+	Team        int             `json:"team"`
+	Cards       []*Card         `json:"cards"`
+	Stats       PlayerGameStats `json:"stats"`
+}
+
+// Returns state of all rooms and running games, running games are suspended
+func (l *Lobby) suspend() *LobbySnapshot {
+	snapshot := &LobbySnapshot{
+		LastClientId: atomic.LoadUint64(&lastClientId),
+		LastRoomId:   atomic.LoadUint64(&lastRoomId),
+		Clients:      make([]*ClientSnapshot, 0),
+		Rooms:        make([]*RoomSnapshot, 0),
+	}
+	for _, c := range l.sessions {
+		snapshot.Clients = append(snapshot.Clients, &ClientSnapshot{
+			Id:           c.id,
+			Nickname:     c.nickname,
+			AccountId:    c.accountId,
+			SessionToken: c.sessionToken,
+		})
+	}
+	for _, r := range l.rooms {
+		snapshot.Rooms = append(snapshot.Rooms, r.suspend())
+	}
+	return snapshot
+}
+
+func (r *Room) suspend() *RoomSnapshot {
+	snapshot := &RoomSnapshot{
+		Id:      r.id,
+		OwnerId: r.owner.client.Id(),
+		Members: make([]*RoomMemberSnapshot, 0),
+		Rules:   r.rules,
+	}
+	for rm := range r.members {
+		memberSnapshot := &RoomMemberSnapshot{
+			ClientId:   rm.client.Id(),
+			Nickname:   rm.client.Nickname(),
+			WantToPlay: rm.wantToPlay,
+			IsPlayer:   rm.isPlayer,
+			IsBot:      rm.isBot,
+			Team:       rm.team,
+		}
+		if botClient, ok := rm.client.(*BotClient); ok {
+			memberSnapshot.BotLevel = botClient.level
+		}
+		snapshot.Members = append(snapshot.Members, memberSnapshot)
+	}
+	if r.match != nil {
+		snapshot.Match = r.match.toSnapshot()
+	}
+	if r.game != nil {
+		snapshot.Game = r.game.requestSuspend()
+	}
+	return snapshot
+}
+
+func (m *Match) toSnapshot() *MatchSnapshot {
+	scores := make([]*MatchScore, 0)
+	for _, score := range m.scores {
+		scores = append(scores, score)
+	}
+	return &MatchSnapshot{
+		Settings:     m.settings,
+		Scores:       scores,
+		GamesPlayed:  m.gamesPlayed,
+		LastLoserIds: m.lastLoserIds,
+		IsOver:       m.isOver,
+	}
+}
+
+// Asks the loop of the game to suspend it, returns nil if the game is not running
+func (g *Game) requestSuspend() *GameSnapshot {
+	snapshotResult := make(chan *GameSnapshot)
+	select {
+	case g.suspendRequests <- snapshotResult:
+		return <-snapshotResult
+	case <-g.loopDone:
+		return nil
+	}
+}
+
+// Stops the clock and returns state of the game, time of the current move is not saved.
+// It is called from the loop of the game.
+func (g *Game) suspend() *GameSnapshot {
+	g.clock.stop()
+	g.gameLogger.LogGameSuspended(g)
+
+	state := g.state
+	snapshot := &GameSnapshot{
+		Id:                            g.id,
+		Players:                       make([]*PlayerSnapshot, 0),
+		Deck:                          state.deck.cards,
+		DiscardPileSize:               state.discardPileSize,
+		TrumpCard:                     state.trumpCard,
+		TrumpCardIsOwnedByPlayerIndex: state.trumpCardIsOwnedByPlayerIndex,
+		AttackerIndex:                 state.attackerIndex,
+		DefenderIndex:                 state.defenderIndex,
+		Battleground:                  state.battleground,
+		DefendingCards:                state.defendingCards,
+		DefenderPickUp:                state.defenderPickUp,
+		RoundsPlayed:                  state.roundsPlayed,
+		FinishingOrder:                state.finishingOrder,
+		Rules:                         g.rules,
+		Seed:                          g.seed,
+		DeckOrder:                     g.deckCommitment.deckOrder,
+		DeckSalt:                      g.deckCommitment.salt,
+		DeckHash:                      g.deckCommitment.hash,
+		TimeBanksSeconds:              make([]int, len(g.players)),
+		StartedAt:                     g.startedAt,
+	}
+	for i, p := range g.players {
+		playerState := g.getPlayerState(i)
+		snapshot.Players = append(snapshot.Players, &PlayerSnapshot{
+			ClientId:    p.client.Id(),
+			IsActive:    playerState.isActive,
+			IsCompleted: playerState.isCompleted,
+			Team:        p.Team,
+			Cards:       playerState.cards,
+			Stats:       playerState.stats,
+		})
+		snapshot.TimeBanksSeconds[i] = int(g.clock.getTimeBankLeft(i).Seconds())
+	}
+	return snapshot
+}
+
+// Recreates rooms and games from the snapshot. Clients are held as disconnected until they resume sessions.
+func (l *Lobby) restore(snapshot *LobbySnapshot) {
+	atomic.StoreUint64(&lastClientId, snapshot.LastClientId)
+	atomic.StoreUint64(&lastRoomId, snapshot.LastRoomId)
+
+	clients := make(map[uint64]*Client, 0)
+	for _, cs := range snapshot.Clients {
+		client := &Client{
+			lobby:        l,
+			nickname:     cs.Nickname,
+			id:           cs.Id,
+			accountId:    cs.AccountId,
+			sessionToken: cs.SessionToken,
+		}
+		if client.accountId != 0 {
+			client.rating = getRatingToShow(l.ratingStore.GetRating(client.accountId))
+		}
+		clients[client.id] = client
+		l.sessions[client.sessionToken] = client
+	}
+
+	for _, rs := range snapshot.Rooms {
+		room := l.restoreRoom(rs, clients)
+		if room == nil {
+			continue
+		}
+		if ownerClient, ok := room.owner.client.(*Client); ok {
+			l.rooms[ownerClient] = room
+		}
+		if room.game != nil {
+			room.game.resume()
+		}
+	}
+
+	for _, client := range clients {
+		c := client
+		c.reconnectTimer = time.AfterFunc(time.Second*ReconnectGraceSeconds, func() {
+			l.expiredSessions <- c
+		})
+	}
+}
+
+func (l *Lobby) restoreRoom(rs *RoomSnapshot, clients map[uint64]*Client) *Room {
+	room := &Room{
+		id:      rs.Id,
+		members: make(map[*RoomMember]bool, 0),
+		lobby:   l,
+		rules:   rs.Rules,
+	}
+
+	membersClients := make(map[uint64]ClientSender, 0)
+	for _, ms := range rs.Members {
+		var memberClient ClientSender
+		if ms.IsBot {
+			// Bots of snapshots without levels play as before
+			botLevel := ms.BotLevel
+			if !isValidBotLevel(botLevel) {
+				botLevel = BotLevelNormal
+			}
+			botClient := newBotClient(ms.ClientId, room, botLevel)
+			botClient.nickname = ms.Nickname
+			memberClient = botClient
+		} else {
+			client, ok := clients[ms.ClientId]
+			if !ok {
+				log.Printf("Client %d of room %d is not found in snapshot", ms.ClientId, rs.Id)
+				continue
+			}
+			client.room = room
+			memberClient = client
+		}
+		member := &RoomMember{memberClient, ms.WantToPlay, ms.IsPlayer, ms.IsBot, ms.Team}
+		room.members[member] = true
+		membersClients[ms.ClientId] = memberClient
+		if ms.ClientId == rs.OwnerId && !ms.IsBot {
+			room.owner = member
+		}
+	}
+	if room.owner == nil {
+		for member := range room.members {
+			if !member.isBot {
+				room.owner = member
+			}
+		}
+	}
+	if room.owner == nil {
+		log.Printf("Room %d has no members in snapshot", rs.Id)
+		return nil
+	}
+
+	if rs.Match != nil {
+		room.match = newMatch(rs.Match.Settings)
+		for _, score := range rs.Match.Scores {
+			room.match.scores[score.MemberId] = score
+		}
+		room.match.gamesPlayed = rs.Match.GamesPlayed
+		room.match.lastLoserIds = rs.Match.LastLoserIds
+		room.match.isOver = rs.Match.IsOver
+	}
+
+	if rs.Game != nil {
+		room.game = room.restoreGame(rs.Game, membersClients)
+	}
+
+	return room
+}
+
+func (r *Room) restoreGame(gs *GameSnapshot, membersClients map[uint64]ClientSender) *Game {
+	players := make([]*Player, 0)
+	for _, ps := range gs.Players {
+		client, ok := membersClients[ps.ClientId]
+		if !ok {
+			log.Printf("Player %d of game %s is not a member of room", ps.ClientId, gs.Id)
+			return nil
+		}
+		player := newPlayer(client, ps.IsActive)
+		player.Team = ps.Team
+		players = append(players, player)
+	}
+
+	game := newGame(r, players, r.lobby.gameLogger, gs.Rules)
+	game.id = gs.Id
+	game.setStatus(GameStatusPlaying)
+	state := game.state
+	for i, ps := range gs.Players {
+		state.players[i].isCompleted = ps.IsCompleted
+		state.players[i].cards = ps.Cards
+		state.players[i].stats = ps.Stats
+	}
+	state.deck = &Deck{cards: gs.Deck}
+	state.discardPileSize = gs.DiscardPileSize
+	state.trumpCard = gs.TrumpCard
+	if gs.TrumpCard != nil {
+		state.trumpSuit = gs.TrumpCard.Suit
+	}
+	state.trumpCardIsOwnedByPlayerIndex = gs.TrumpCardIsOwnedByPlayerIndex
+	state.attackerIndex = gs.AttackerIndex
+	state.defenderIndex = gs.DefenderIndex
+	state.battleground = gs.Battleground
+	state.defendingCards = gs.DefendingCards
+	state.defenderPickUp = gs.DefenderPickUp
+	state.roundsPlayed = gs.RoundsPlayed
+	state.finishingOrder = gs.FinishingOrder
+	game.seed = gs.Seed
+	game.startedAt = gs.StartedAt
+	game.rng = newDeckRand(gs.Seed)
+	game.deckCommitment = &DeckCommitment{
+		deckOrder: gs.DeckOrder,
+		salt:      gs.DeckSalt,
+		hash:      gs.DeckHash,
+	}
+	for i, timeBankSeconds := range gs.TimeBanksSeconds {
+		game.clock.timeBanks[i] = time.Second * time.Duration(timeBankSeconds)
+	}
+
+	return game
+}
+
+// Continues the restored game: starts the clock and sends state to bots, people get it when they resume sessions
+func (g *Game) resume() {
+	g.gameLogger.LogGameRestored(g)
+	g.restartMoveTimer()
+
+	for i, p := range g.players {
+		if _, ok := p.client.(*BotClient); ok {
+			p.sendEvent(g.newPlayersEvent(i))
+			gse := &GameStartedEvent{GameRules: g.rules, DeckHash: g.deckCommitment.hash}
+			gse.GameStateInfo = g.getGameStateInfo(p)
+			p.sendEvent(gse)
+		}
+	}
+
+	go g.loop()
+}
+
+func saveLobbySnapshot(path string, snapshot *LobbySnapshot) error {
+	return writeJsonFile(path, snapshot)
+}
+
+// Reads snapshot from file and removes the file, so the same state is not restored twice. Returns nil without file.
+func loadLobbySnapshot(path string) (*LobbySnapshot, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot LobbySnapshot
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, os.Remove(path)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspendAndRestoreGame(t *testing.T) {
+	lobby, room, clients := newTestLobbyWithGame()
+	game := room.game
+	game.state.players[0].cards = []*Card{{"7", "♥"}, {"8", "♥"}}
+	game.state.players[1].cards = []*Card{{"9", "♦"}}
+	game.applyAction(newAttackAction(0, &Card{"7", "♥"}))
+	room.match = newMatch(&MatchSettings{Mode: MatchModeGames, Target: 3})
+	room.match.getScore(clients[1]).Losses = 1
+	go game.loop()
+
+	snapshotJson, err := json.Marshal(lobby.suspend())
+	assert := assert.New(t)
+	assert.Nil(err)
+
+	var snapshot LobbySnapshot
+	assert.Nil(json.Unmarshal(snapshotJson, &snapshot))
+	restoredLobby := newLobby(&TestGameLogger{}, &AccountFileStore{accounts: make([]*Account, 0)}, &RatingFileStore{ratings: make(map[uint64]*PlayerRating, 0)}, &StatsFileStore{stats: make(map[uint64]*PlayerStats, 0)})
+	restoredLobby.restore(&snapshot)
+	for _, c := range restoredLobby.sessions {
+		c.reconnectTimer.Stop()
+	}
+
+	assert.Equal(1, len(restoredLobby.rooms))
+	var restoredRoom *Room
+	for _, r := range restoredLobby.rooms {
+		restoredRoom = r
+	}
+	assert.Equal(room.Id(), restoredRoom.Id())
+	assert.Equal(clients[0].Id(), restoredRoom.owner.client.Id())
+	assert.Equal(2, len(restoredRoom.members))
+	assert.Equal(1, restoredRoom.match.getScore(clients[1]).Losses)
+
+	restoredGame := restoredRoom.game
+	assert.Equal(game.id, restoredGame.id)
+	assert.Equal(GameStatusPlaying, restoredGame.status)
+	assert.Equal(game.state.deck.asString(), restoredGame.state.deck.asString())
+	assert.Equal(game.state.players[0].cards, restoredGame.state.players[0].cards)
+	assert.Equal(game.state.battleground, restoredGame.state.battleground)
+	assert.Equal(game.state.attackerIndex, restoredGame.state.attackerIndex)
+	assert.Equal(game.state.defenderIndex, restoredGame.state.defenderIndex)
+	assert.Equal("Bob", restoredGame.players[1].Name)
+
+	newClient := &Client{id: 10, lobby: restoredLobby, isValid: true}
+	restoredLobby.onResumeCommand(newClient, clients[1].sessionToken)
+	assert.Equal(clients[1].Id(), newClient.Id())
+	assert.Equal(newClient, restoredGame.players[1].client)
+}
+
+func TestSuspendedGameWithoutTrumpCard(t *testing.T) {
+	_, room, clients := newTestLobbyWithGame()
+	game := room.game
+	game.state.trumpCard = nil
+	go game.loop()
+
+	gameSnapshot := game.requestSuspend()
+	assert := assert.New(t)
+	assert.NotNil(gameSnapshot)
+	assert.Nil(game.requestSuspend())
+
+	membersClients := map[uint64]ClientSender{clients[0].Id(): clients[0], clients[1].Id(): clients[1]}
+	restoredGame := room.restoreGame(gameSnapshot, membersClients)
+	assert.Nil(restoredGame.state.trumpCard)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// PlayerGameStats contains counters of a player in one game
+type PlayerGameStats struct {
+	RoundsDefended     int `json:"roundsDefended"`
+	PickUps            int `json:"pickUps"`
+	SuccessfulDefences int `json:"successfulDefences"`
+	TrumpsPlayed       int `json:"trumpsPlayed"`
+}
+
+// PlayerGameResult contains result and counters of an account in the ended game
+type PlayerGameResult struct {
+	AccountId   uint64
+	Place       int
+	IsLoser     bool
+	GameSeconds int
+	Stats       PlayerGameStats
+}
+
+// PlayerStats contains counters of an account summed up over all games
+type PlayerStats struct {
+	AccountId          uint64    `json:"accountId"`
+	GamesPlayed        int       `json:"gamesPlayed"`
+	Losses             int       `json:"losses"`
+	PlacesSum          int       `json:"placesSum"`
+	RoundsDefended     int       `json:"roundsDefended"`
+	PickUps            int       `json:"pickUps"`
+	SuccessfulDefences int       `json:"successfulDefences"`
+	TrumpsPlayed       int       `json:"trumpsPlayed"`
+	GamesSeconds       int       `json:"gamesSeconds"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+	// Games of the last days by dates for leaderboards of periods
+	RecentDays map[string]*PeriodStats `json:"recentDays"`
+}
+
+// PeriodStats contains number of games and losses of an account in a period
+type PeriodStats struct {
+	GamesPlayed int `json:"gamesPlayed"`
+	Losses      int `json:"losses"`
+}
+
+// PlayerStatsInfo contains stats of an account for API
+type PlayerStatsInfo struct {
+	AccountId          uint64  `json:"accountId"`
+	Nickname           string  `json:"nickname"`
+	Rating             int     `json:"rating"`
+	GamesPlayed        int     `json:"gamesPlayed"`
+	Losses             int     `json:"losses"`
+	AveragePlace       float64 `json:"averagePlace"`
+	PickUpRate         float64 `json:"pickUpRate"`
+	SuccessfulDefences int     `json:"successfulDefences"`
+	TrumpsPlayed       int     `json:"trumpsPlayed"`
+	AverageGameSeconds float64 `json:"averageGameSeconds"`
+}
+
+// StatsStore keeps stats of accounts
+type StatsStore interface {
+	// Returns stats of the account or nil if the account has not played yet
+	GetStats(accountId uint64) *PlayerStats
+	// Adds results of the ended game to stats of accounts
+	AddGameResults(results []*PlayerGameResult) error
+	// Returns stats of all accounts
+	GetAllStats() []*PlayerStats
+}
+
+// StatsFileStore keeps stats in a local JSON file
+type StatsFileStore struct {
+	path  string
+	mutex sync.Mutex
+	stats map[uint64]*PlayerStats
+}
+
+// NewStatsFileStore loads stats from the file; the file is created with the first ended game
+func NewStatsFileStore(path string) (*StatsFileStore, error) {
+	s := &StatsFileStore{
+		path:  path,
+		stats: make(map[uint64]*PlayerStats, 0),
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	statsList := make([]*PlayerStats, 0)
+	if err := json.Unmarshal(contents, &statsList); err != nil {
+		return nil, fmt.Errorf("cannot parse stats file %s: %s", path, err)
+	}
+	for _, stats := range statsList {
+		s.stats[stats.AccountId] = stats
+	}
+
+	return s, nil
+}
+
+// GetStats returns stats of the account or nil if the account has not played yet
+func (s *StatsFileStore) GetStats(accountId uint64) *PlayerStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats, ok := s.stats[accountId]
+	if !ok {
+		return nil
+	}
+	return stats.copy()
+}
+
+// GetAllStats returns stats of all accounts
+func (s *StatsFileStore) GetAllStats() []*PlayerStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statsList := make([]*PlayerStats, 0)
+	for _, stats := range s.stats {
+		statsList = append(statsList, stats.copy())
+	}
+	return statsList
+}
+
+// AddGameResults adds results of the ended game to stats of accounts
+func (s *StatsFileStore) AddGameResults(results []*PlayerGameResult) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	day := getStatsDay(now)
+	for _, result := range results {
+		stats, ok := s.stats[result.AccountId]
+		if !ok {
+			stats = &PlayerStats{AccountId: result.AccountId}
+			s.stats[result.AccountId] = stats
+		}
+		if stats.RecentDays == nil {
+			stats.RecentDays = make(map[string]*PeriodStats, 0)
+		}
+		dayStats, ok := stats.RecentDays[day]
+		if !ok {
+			dayStats = &PeriodStats{}
+			stats.RecentDays[day] = dayStats
+		}
+		stats.removeOldDays(now)
+
+		stats.GamesPlayed++
+		dayStats.GamesPlayed++
+		if result.IsLoser {
+			stats.Losses++
+			dayStats.Losses++
+		}
+		stats.PlacesSum += result.Place
+		stats.RoundsDefended += result.Stats.RoundsDefended
+		stats.PickUps += result.Stats.PickUps
+		stats.SuccessfulDefences += result.Stats.SuccessfulDefences
+		stats.TrumpsPlayed += result.Stats.TrumpsPlayed
+		stats.GamesSeconds += result.GameSeconds
+		stats.UpdatedAt = now
+	}
+
+	statsList := make([]*PlayerStats, 0)
+	for _, stats := range s.stats {
+		statsList = append(statsList, stats)
+	}
+
+	return writeJsonFile(s.path, statsList)
+}
+
+func (s *PlayerStats) copy() *PlayerStats {
+	statsCopy := *s
+	statsCopy.RecentDays = make(map[string]*PeriodStats, 0)
+	for day, dayStats := range s.RecentDays {
+		dayStatsCopy := *dayStats
+		statsCopy.RecentDays[day] = &dayStatsCopy
+	}
+	return &statsCopy
+}
+
+// Returns games of the account in the last days including the given time
+func (s *PlayerStats) getPeriodStats(now time.Time, daysNum int) *PeriodStats {
+	periodStats := &PeriodStats{}
+	for i := 0; i < daysNum; i++ {
+		dayStats, ok := s.RecentDays[getStatsDay(now.AddDate(0, 0, -i))]
+		if ok {
+			periodStats.GamesPlayed += dayStats.GamesPlayed
+			periodStats.Losses += dayStats.Losses
+		}
+	}
+	return periodStats
+}
+
+// Keeps days which are needed for the longest period of leaderboards
+func (s *PlayerStats) removeOldDays(now time.Time) {
+	oldestDay := getStatsDay(now.AddDate(0, 0, -(LeaderboardWeekDays - 1)))
+	for day := range s.RecentDays {
+		if day < oldestDay {
+			delete(s.RecentDays, day)
+		}
+	}
+}
+
+func getStatsDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func (s *PlayerStats) toPlayerStatsInfo(nickname string, rating int) *PlayerStatsInfo {
+	info := &PlayerStatsInfo{
+		AccountId:          s.AccountId,
+		Nickname:           nickname,
+		Rating:             rating,
+		GamesPlayed:        s.GamesPlayed,
+		Losses:             s.Losses,
+		SuccessfulDefences: s.SuccessfulDefences,
+		TrumpsPlayed:       s.TrumpsPlayed,
+	}
+	if s.GamesPlayed > 0 {
+		info.AveragePlace = float64(s.PlacesSum) / float64(s.GamesPlayed)
+		info.AverageGameSeconds = float64(s.GamesSeconds) / float64(s.GamesPlayed)
+	}
+	if s.RoundsDefended > 0 {
+		info.PickUpRate = float64(s.PickUps) / float64(s.RoundsDefended)
+	}
+	return info
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStatsFileStore(t *testing.T) (store *StatsFileStore, path string) {
+	dir, err := ioutil.TempDir("", "durak_stats")
+	if err != nil {
+		t.Fatalf("Cannot create temp dir: %s", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path = filepath.Join(dir, "stats.json")
+	store, err = NewStatsFileStore(path)
+	if err != nil {
+		t.Fatalf("Cannot create stats store: %s", err)
+	}
+	return store, path
+}
+
+func TestStatsAreSummedUp(t *testing.T) {
+	store, path := newTestStatsFileStore(t)
+	store.AddGameResults([]*PlayerGameResult{{
+		AccountId:   7,
+		Place:       1,
+		GameSeconds: 100,
+		Stats:       PlayerGameStats{RoundsDefended: 3, PickUps: 1, SuccessfulDefences: 2, TrumpsPlayed: 4},
+	}})
+	store.AddGameResults([]*PlayerGameResult{{
+		AccountId:   7,
+		Place:       2,
+		IsLoser:     true,
+		GameSeconds: 200,
+		Stats:       PlayerGameStats{RoundsDefended: 1, PickUps: 1, TrumpsPlayed: 1},
+	}})
+
+	reloadedStore, err := NewStatsFileStore(path)
+	assert := assert.New(t)
+	assert.Nil(err)
+
+	info := reloadedStore.GetStats(7).toPlayerStatsInfo("Alice", 1516)
+	expected := &PlayerStatsInfo{
+		AccountId:          7,
+		Nickname:           "Alice",
+		Rating:             1516,
+		GamesPlayed:        2,
+		Losses:             1,
+		AveragePlace:       1.5,
+		PickUpRate:         0.5,
+		SuccessfulDefences: 2,
+		TrumpsPlayed:       5,
+		AverageGameSeconds: 150,
+	}
+	assert.Equal(expected, info)
+}
+
+func TestGameCountsDefencesAndTrumps(t *testing.T) {
+	game := newTestGame(
+		[]*Card{{"7", "♥"}, {"8", "♥"}, {"9", "♣"}},
+		[]*Card{{"9", "♥"}, {"7", "♠"}, {"10", "♦"}},
+	)
+	game.applyAction(newAttackAction(0, &Card{"7", "♥"}))
+	game.applyAction(newDefendAction(1, &Card{"7", "♥"}, &Card{"7", "♠"}))
+	game.applyAction(newCompleteAction(0))
+	game.applyAction(newCompleteAction(1))
+
+	game.applyAction(newAttackAction(1, &Card{"9", "♥"}))
+	game.applyAction(newPickUpAction(0))
+	game.applyAction(newCompleteAction(1))
+
+	assert := assert.New(t)
+	assert.Equal(PlayerGameStats{RoundsDefended: 1, SuccessfulDefences: 1, TrumpsPlayed: 1}, game.state.players[1].stats)
+	assert.Equal(PlayerGameStats{RoundsDefended: 1, PickUps: 1}, game.state.players[0].stats)
+}
+
+func TestApiPlayerStats(t *testing.T) {
+	lobby, _, _ := newTestLobbyWithGame()
+	accountStore, _ := newTestAccountFileStore(t)
+	statsStore, _ := newTestStatsFileStore(t)
+	lobby.accountStore = accountStore
+	lobby.statsStore = statsStore
+	account, _, _ := accountStore.Register("Alice", "secret1")
+	statsStore.AddGameResults([]*PlayerGameResult{{AccountId: account.Id, Place: 1, GameSeconds: 60}})
+
+	recorder := httptest.NewRecorder()
+	serveApiPlayers(lobby, recorder, httptest.NewRequest("GET", "/api/players/1/stats", nil))
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, recorder.Code)
+	var info PlayerStatsInfo
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &info))
+	assert.Equal("Alice", info.Nickname)
+	assert.Equal(1, info.GamesPlayed)
+	assert.Equal(60.0, info.AverageGameSeconds)
+
+	recorder = httptest.NewRecorder()
+	serveApiPlayers(lobby, recorder, httptest.NewRequest("GET", "/api/players/2/stats", nil))
+	assert.Equal(http.StatusNotFound, recorder.Code)
+}
+
+func TestLobbyAddsStatsOfEndedGame(t *testing.T) {
+	lobby, room, _ := newTestLobbyWithGame()
+	statsStore, _ := newTestStatsFileStore(t)
+	lobby.statsStore = statsStore
+	room.game.players[0].AccountId = 7
+	room.game.state.finishingOrder = [][]int{{0}}
+	room.game.state.loserIndex = 1
+	room.game.state.endReason = GameEndReasonLoser
+
+	lobby.onGameEnded(room.game)
+
+	assert.Eventually(t, func() bool {
+		stats := statsStore.GetStats(7)
+		return stats != nil && stats.GamesPlayed == 1 && stats.Losses == 0
+	}, time.Second, time.Millisecond*10)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Writes value as JSON to temporary file and replaces the file with it, so the file is never written partially
+func writeJsonFile(path string, v interface{}) error {
+	contents, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, contents, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}